@@ -41,12 +41,32 @@ type KubeConfig struct {
 	Namespace string `json:"namespace,omitempty"`
 }
 
+// Transport defines how requests are sent to a source.
+type Transport struct {
+	// CertSecretRef points to a Secret in the namespace of the Bootstrap with a CA bundle under `ca.crt` to verify
+	// the server with, and a client certificate and key under `tls.crt` and `tls.key` for mutual TLS. Every key is
+	// optional, but a client certificate requires its key.
+	// +optional
+	CertSecretRef *v1.LocalObjectReference `json:"certSecretRef,omitempty"`
+	// ProxySecretRef points to a Secret in the namespace of the Bootstrap with the `address` of an HTTP proxy to
+	// send requests through, and optionally a `username` and `password` to authenticate with it.
+	// +optional
+	ProxySecretRef *v1.LocalObjectReference `json:"proxySecretRef,omitempty"`
+	// Timeout of a request to the source, including retries. Defaults to 60s.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ms|s|m|h))+$"
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
 // GitHub defines a GitHub type source where the CRD is coming from `release` section of a GitHub repository.
 type GitHub struct {
-	// BaseURL is used for the GitHub url. Defaults to github.com if not defined.
+	// BaseURL is used for the GitHub url. Defaults to github.com if not defined. Release assets of sources without
+	// SecretRef are downloaded from it, sources with SecretRef download them through the API at BaseAPIURL.
 	// +optional
 	BaseURL string `json:"baseURL,omitempty"`
-	// BaseAPIURL is used for the GitHub API url. Defaults to api.github.com if not defined.
+	// BaseAPIURL is used for the GitHub API url. Defaults to api.github.com if not defined. For GitHub Enterprise it's
+	// usually https://<host>/api/v3.
 	// +optional
 	BaseAPIURL string `json:"baseAPIURL,omitempty"`
 
@@ -58,12 +78,15 @@ type GitHub struct {
 	// +required
 	Repo string `json:"repo"`
 
-	// SecretRef contains a pointed to a Token in case the repository is private.
+	// SecretRef contains a pointer to a secret with a token, or the `appID`, `installationID` and `privateKey` of a
+	// GitHub App installation, in case the repository is private.
 	// +optional
 	SecretRef *v1.LocalObjectReference `json:"secretRef,omitempty"`
 	// Manifest defines the name of the manifest that contains the CRD definitions on the GitHub release page.
 	// +required
 	Manifest string `json:"manifest"`
+
+	Transport `json:",inline"`
 }
 
 // GitLab defines a GitLab type source where the CRD is coming from `release` section of a GitLab repository.
@@ -87,6 +110,8 @@ type GitLab struct {
 	// Manifest defines the name of the manifest that contains the CRD definitions on the GitLab release page.
 	// +required
 	Manifest string `json:"manifest"`
+
+	Transport `json:",inline"`
 }
 
 // Helm defines a Helm type source where the CRD is coming from a helm release with a version.
@@ -103,6 +128,8 @@ type Helm struct {
 	// SecretRef contains a pointer to a secret that contains any needed credentials to access the helm repository.
 	// +optional
 	SecretRef *v1.LocalObjectReference `json:"secretRef,omitempty"`
+
+	Transport `json:",inline"`
 }
 
 // ConfigMap defines a reference to a configmap which hold the CRD information. Version is taken from a version field.
@@ -120,9 +147,12 @@ type URL struct {
 	// URL defines the URL from which do download the YAML content from.
 	// +required
 	URL string `json:"url"`
-	// SecretRef contains a pointed to a Token in case the URL isn't public.
+	// SecretRef contains a pointer to a secret with the credentials in case the URL isn't public. The `authType` key
+	// of the secret selects how they are sent: `bearer`, `basic`, `header` or `query`.
 	// +optional
 	SecretRef *v1.LocalObjectReference `json:"secretRef,omitempty"`
+
+	Transport `json:",inline"`
 }
 
 // Source defines options from where to fetch CRD content.
@@ -144,6 +174,24 @@ type Source struct {
 	URL *URL `json:"url,omitempty"`
 }
 
+// GetTransport returns the transport settings of the defined source. ConfigMaps don't have any.
+func (in *Source) GetTransport() *Transport {
+	switch {
+	case in == nil:
+		return nil
+	case in.GitHub != nil:
+		return &in.GitHub.Transport
+	case in.GitLab != nil:
+		return &in.GitLab.Transport
+	case in.Helm != nil:
+		return &in.Helm.Transport
+	case in.URL != nil:
+		return &in.URL.Transport
+	default:
+		return nil
+	}
+}
+
 // Version defines options to look at when trying to determine what version is allowed to be fetched / applied.
 type Version struct {
 	// Semver defines a possible constraint like `>=v1`.
@@ -155,6 +203,115 @@ type Version struct {
 	Digest string `json:"digest,omitempty"`
 }
 
+// Template defines where to find objects to validate new CRD versions against. Every object is matched to a CRD
+// and to a version of that CRD through its apiVersion and kind.
+type Template struct {
+	// Inline defines a single object.
+	// +optional
+	Inline *apiextensionsv1.JSON `json:"inline,omitempty"`
+
+	// ConfigMapRef points to a ConfigMap in the namespace of the Bootstrap. Every key in the ConfigMap is
+	// read as YAML content which may contain multiple objects.
+	// +optional
+	ConfigMapRef *v1.LocalObjectReference `json:"configMapRef,omitempty"`
+
+	// SourcePath defines the location of sample objects in the fetched source. For Helm this is a directory
+	// in the chart such as `samples`, for GitHub and GitLab it's the name of a release asset and for a
+	// ConfigMap source it's a key in the ConfigMap.
+	// +optional
+	SourcePath string `json:"sourcePath,omitempty"`
+}
+
+// Notification defines a webhook to send notifications about upgrades and failures to.
+type Notification struct {
+	// SecretRef points to a Secret in the namespace of the Bootstrap. The `address` key holds the URL of the
+	// webhook. The optional `token` key holds a key that is used to sign the payload with HMAC-SHA256.
+	// +required
+	SecretRef v1.LocalObjectReference `json:"secretRef"`
+
+	// Format defines the format of the payload. Defaults to generic, which sends the notification as is.
+	// +kubebuilder:validation:Enum=generic;slack;msteams
+	// +optional
+	Format string `json:"format,omitempty"`
+}
+
+// Dependency defines another Bootstrap that has to be ready before this Bootstrap is reconciled.
+type Dependency struct {
+	// Name of the Bootstrap.
+	// +required
+	Name string `json:"name"`
+
+	// Namespace of the Bootstrap. Defaults to the namespace of the dependent Bootstrap.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// MinRevision defines the lowest revision the Bootstrap must have applied. Revisions are compared as
+	// semantic versions. If either of them isn't a semantic version, such as a digest, they must be equal.
+	// +optional
+	MinRevision string `json:"minRevision,omitempty"`
+}
+
+// Target defines a cluster the CRDs are applied to.
+type Target struct {
+	// Name identifies the target in the status.
+	// +required
+	Name string `json:"name"`
+
+	// KubeConfig defines the kubeconfig used to access the cluster.
+	// +required
+	KubeConfig KubeConfig `json:"kubeConfig"`
+}
+
+// Stage defines a group of targets that has to be rolled out to before the following stages.
+type Stage struct {
+	// Name identifies the stage in the status.
+	// +required
+	Name string `json:"name"`
+
+	// Targets defines patterns of the names of the targets in the stage, such as `canary-*`. A target belongs to
+	// the first stage that matches it.
+	// +required
+	Targets []string `json:"targets"`
+
+	// SoakDuration defines how long the CRDs must stay ready in every target of the stage before the next
+	// stage is rolled out to.
+	// +optional
+	SoakDuration metav1.Duration `json:"soakDuration,omitempty"`
+}
+
+// Targets defines the clusters the CRDs are rolled out to and how the rollout progresses.
+type Targets struct {
+	// Clusters defines the target clusters in the order they are rolled out to.
+	// +optional
+	Clusters []Target `json:"clusters,omitempty"`
+
+	// Selector selects Secrets in the namespace of the Bootstrap that contain a kubeconfig in the `value` key.
+	// Every selected Secret is a target named after the Secret. They are rolled out to after the clusters, in
+	// the order of their names.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// ServiceAccount defines the service account impersonated in the clusters of the selected Secrets.
+	// +optional
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+
+	// MaxConcurrent defines how many targets are rolled out to at the same time. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConcurrent int `json:"maxConcurrent,omitempty"`
+
+	// StopOnFailure stops the rollout once a target fails, so the remaining targets keep their CRDs.
+	// +optional
+	StopOnFailure bool `json:"stopOnFailure,omitempty"`
+
+	// Stages rolls a new revision out to the targets one stage after the other. The next stage is only rolled
+	// out to once the CRDs stayed ready in every target of the previous stage for its soak duration, and the
+	// rollout halts if a target fails. Every target must belong to a stage. If no stages are defined, all targets
+	// are rolled out to at once.
+	// +optional
+	Stages []Stage `json:"stages,omitempty"`
+}
+
 // BootstrapSpec defines the desired state of Bootstrap.
 type BootstrapSpec struct {
 	// Interval defines the regular interval at which a poll for new version should happen.
@@ -171,10 +328,25 @@ type BootstrapSpec struct {
 	// +optional
 	Version Version `json:"version,omitempty"`
 
-	// Template defines a set of values to test a new version against.
+	// Template defines a set of values keyed by Kind to test a new version against. If a value defines an
+	// apiVersion it's only validated against that version of the CRD, otherwise against all of them.
 	// +optional
 	Template map[string]*apiextensionsv1.JSON `json:"template,omitempty"`
 
+	// Templates defines a list of inline or external objects to test a new version against.
+	// +optional
+	Templates []Template `json:"templates,omitempty"`
+
+	// OldTemplate defines the previous values of a template keyed by the same Kind. If set, transition rules
+	// in the CRD's x-kubernetes-validations that reference oldSelf are evaluated against it.
+	// +optional
+	OldTemplate map[string]*apiextensionsv1.JSON `json:"oldTemplate,omitempty"`
+
+	// StrictTemplates fails the validation if a template doesn't match any CRD of the fetched content. By default
+	// such templates are skipped with a warning event, as their CRD may be installed from another source.
+	// +optional
+	StrictTemplates bool `json:"strictTemplates,omitempty"`
+
 	// ContinueOnValidationError will still apply a CRD even if the validation failed for it.
 	// +optional
 	ContinueOnValidationError bool `json:"continueOnValidationError,omitempty"`
@@ -191,10 +363,98 @@ type BootstrapSpec struct {
 	// KubeConfig defines a kubeconfig that could be used to access another cluster and apply a CRD there.
 	// +optional
 	KubeConfig *KubeConfig `json:"kubeConfig,omitempty"`
+
+	// Notification defines a webhook that is notified when the CRDs are upgraded or an upgrade fails.
+	// +optional
+	Notification *Notification `json:"notification,omitempty"`
+
+	// Suspend stops the reconciliation of the Bootstrap. Deleting a suspended Bootstrap still prunes its CRDs
+	// if prune is set.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DependsOn defines Bootstraps that must be ready before this Bootstrap is reconciled. Until they are,
+	// the Ready condition is set to DependencyNotReady and the reconcile is retried.
+	// +optional
+	DependsOn []Dependency `json:"dependsOn,omitempty"`
+
+	// Targets defines clusters the CRDs are applied to instead of the cluster the controller runs in. It
+	// can't be used together with kubeConfig.
+	// +optional
+	Targets *Targets `json:"targets,omitempty"`
+}
+
+// StagePhase describes the progress of a revision through a stage.
+type StagePhase string
+
+const (
+	// StagePending means the revision wasn't rolled out to the stage yet.
+	StagePending StagePhase = "Pending"
+	// StageSoaking means the revision was applied to every target of the stage and the stage is soaking.
+	StageSoaking StagePhase = "Soaking"
+	// StageCompleted means the revision was applied to every target of the stage and the soak duration passed.
+	StageCompleted StagePhase = "Completed"
+	// StageFailed means the revision couldn't be applied to a target of the stage or its CRDs aren't ready.
+	StageFailed StagePhase = "Failed"
+)
+
+// StageStatus defines the observed state of a rollout stage.
+type StageStatus struct {
+	// Name of the stage.
+	Name string `json:"name"`
+
+	// Revision is the revision that is rolled out through the stage.
+	// +optional
+	Revision string `json:"revision,omitempty"`
+
+	// Phase of the revision in the stage.
+	// +kubebuilder:validation:Enum=Pending;Soaking;Completed;Failed
+	Phase StagePhase `json:"phase"`
+
+	// ReadyTargets is the number of targets in the stage that have the revision applied.
+	ReadyTargets int `json:"readyTargets"`
+
+	// TotalTargets is the number of targets in the stage.
+	TotalTargets int `json:"totalTargets"`
+
+	// SoakStartedAt is the time at which the revision was applied to every target of the stage.
+	// +optional
+	SoakStartedAt *metav1.Time `json:"soakStartedAt,omitempty"`
+
+	// Message describes the progress of the stage.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// TargetStatus defines the observed state of a target cluster.
+type TargetStatus struct {
+	// Name of the target.
+	Name string `json:"name"`
+
+	// Ready reports whether the last attempted revision was applied to the target.
+	Ready bool `json:"ready"`
+
+	// Reason is the reason the last rollout to the target failed.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message describes the result of the last rollout to the target.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastAppliedRevision is the revision that was last applied to the target.
+	// +optional
+	LastAppliedRevision string `json:"lastAppliedRevision,omitempty"`
+
+	// BreakingChanges contains the breaking schema changes detected against the CRDs in the target.
+	// +optional
+	BreakingChanges []string `json:"breakingChanges,omitempty"`
 }
 
 // BootstrapStatus defines the observed state of Bootstrap.
 type BootstrapStatus struct {
+	meta.ReconcileRequestStatus `json:",inline"`
+
 	// ObservedGeneration is the last reconciled generation.
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
@@ -220,6 +480,14 @@ type BootstrapStatus struct {
 	// BreakingChanges contains detected breaking schema changes when UpdatePolicy is set.
 	// +optional
 	BreakingChanges []string `json:"breakingChanges,omitempty"`
+
+	// Targets contains the status of every target cluster.
+	// +optional
+	Targets []TargetStatus `json:"targets,omitempty"`
+
+	// Stages contains the progress of the last attempted revision through the rollout stages.
+	// +optional
+	Stages []StageStatus `json:"stages,omitempty"`
 }
 
 // GetConditions returns the conditions of the ComponentVersion.
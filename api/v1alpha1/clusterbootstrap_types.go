@@ -0,0 +1,93 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ClusterBootstrapKind is the kind of the ClusterBootstrap.
+	ClusterBootstrapKind = "ClusterBootstrap"
+
+	// ClusterBootstrapOwnerLabelKey is set on CRDs applied by a ClusterBootstrap. It differs from the label of
+	// a Bootstrap, so a Bootstrap with the same name can't claim or prune them.
+	ClusterBootstrapOwnerLabelKey = "delivery.crd-bootstrap.cluster-owned"
+)
+
+// GetConditions returns the conditions of the ClusterBootstrap.
+func (in *ClusterBootstrap) GetConditions() []metav1.Condition {
+	return in.Status.Conditions
+}
+
+// SetConditions sets the conditions of the ClusterBootstrap.
+func (in *ClusterBootstrap) SetConditions(conditions []metav1.Condition) {
+	in.Status.Conditions = conditions
+}
+
+// GetRequeueAfter returns the duration after which the ClusterBootstrap must be
+// reconciled again.
+func (in *ClusterBootstrap) GetRequeueAfter() time.Duration {
+	return in.Spec.Interval.Duration
+}
+
+// AsBootstrap returns a Bootstrap with the spec and status of the ClusterBootstrap. References to Secrets,
+// ConfigMaps and ServiceAccounts of the Bootstrap are resolved in the given namespace. The kind is set to
+// ClusterBootstrap, so events recorded for it refer to the ClusterBootstrap.
+func (in *ClusterBootstrap) AsBootstrap(namespace string) *Bootstrap {
+	obj := &Bootstrap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: GroupVersion.String(),
+			Kind:       ClusterBootstrapKind,
+		},
+		ObjectMeta: *in.ObjectMeta.DeepCopy(),
+		Spec:       *in.Spec.DeepCopy(),
+		Status:     *in.Status.DeepCopy(),
+	}
+	obj.Namespace = namespace
+
+	return obj
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description=""
+//+kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].message",description=""
+
+// ClusterBootstrap is the Schema for the clusterbootstraps API. It's a cluster-scoped Bootstrap that only
+// cluster administrators can manage. References to Secrets, ConfigMaps and ServiceAccounts are resolved in the
+// namespace of the controller.
+type ClusterBootstrap struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BootstrapSpec   `json:"spec,omitempty"`
+	Status BootstrapStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterBootstrapList contains a list of ClusterBootstrap.
+type ClusterBootstrapList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterBootstrap `json:"items"`
+}
@@ -7,6 +7,29 @@ const (
 	PasswordKey = "password"
 	// DockerJSONConfigKey represents the name of the key for dockerjsonconfig field.
 	DockerJSONConfigKey = ".dockerconfigjson"
+	// CACertKey represents the name of the key for the CA bundle to verify servers with.
+	CACertKey = "ca.crt"
+	// TLSCertKey represents the name of the key for the client certificate.
+	TLSCertKey = "tls.crt"
+	// TLSKeyKey represents the name of the key for the private key of the client certificate.
+	TLSKeyKey = "tls.key"
+	// ProxyAddressKey represents the name of the key for the address of a proxy.
+	ProxyAddressKey = "address"
+	// TokenKey represents the name of the key for the token to authenticate with.
+	TokenKey = "token"
+	// AuthTypeKey represents the name of the key that selects how to authenticate with the token or username and
+	// password of a secret.
+	AuthTypeKey = "authType"
+	// HeaderNameKey represents the name of the key for the name of the header to send the token in.
+	HeaderNameKey = "headerName"
+	// QueryParamKey represents the name of the key for the name of the query parameter to send the token in.
+	QueryParamKey = "queryParam"
+	// AppIDKey represents the name of the key for the ID of a GitHub App.
+	AppIDKey = "appID"
+	// InstallationIDKey represents the name of the key for the ID of an installation of a GitHub App.
+	InstallationIDKey = "installationID"
+	// PrivateKeyKey represents the name of the key for the PEM encoded private key of a GitHub App.
+	PrivateKeyKey = "privateKey"
 )
 
 const (
@@ -0,0 +1,87 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CRDRule matches CRDs. Every defined field must match for the rule to match. Groups and names are glob
+// patterns such as `*.k8s.io`.
+type CRDRule struct {
+	// Groups defines patterns of the API group of the CRD.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+
+	// Names defines patterns of the name of the CRD, such as `*.example.com`.
+	// +optional
+	Names []string `json:"names,omitempty"`
+
+	// Scopes defines the scopes of the resources of the CRD.
+	// +optional
+	Scopes []apiextensionsv1.ResourceScope `json:"scopes,omitempty"`
+}
+
+// CRDPolicySpec defines the desired state of CRDPolicy.
+type CRDPolicySpec struct {
+	// Namespaces defines patterns of the namespaces of the Bootstraps the policy applies to. If it's empty, the policy
+	// applies to Bootstraps in every namespace and to ClusterBootstraps.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector selects the Bootstraps the policy applies to by the labels of their namespace. If it's set,
+	// the policy doesn't apply to ClusterBootstraps.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Selector selects the Bootstraps and ClusterBootstraps the policy applies to by their labels. If it's not set,
+	// the policy applies to all of them. The labels of a Bootstrap are controlled by whoever can edit it, so a
+	// selector isn't a security boundary between tenants. Use namespaces or namespaceSelector for that.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Allow defines the CRDs that may be installed. If it's empty, every CRD that isn't denied is allowed.
+	// +optional
+	Allow []CRDRule `json:"allow,omitempty"`
+
+	// Deny defines the CRDs that may not be installed, even if they are allowed.
+	// +optional
+	Deny []CRDRule `json:"deny,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// CRDPolicy is the Schema for the crdpolicies API. It restricts the CRDs Bootstraps and ClusterBootstraps may
+// install. A CRD is only installed if every policy that applies to the Bootstrap allows it.
+type CRDPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec CRDPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CRDPolicyList contains a list of CRDPolicy.
+type CRDPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []CRDPolicy `json:"items"`
+}
@@ -37,7 +37,7 @@ var (
 )
 
 func addKnownTypes(scheme *runtime.Scheme) error {
-	scheme.AddKnownTypes(GroupVersion, &Bootstrap{}, &BootstrapList{})
+	scheme.AddKnownTypes(GroupVersion, &Bootstrap{}, &BootstrapList{}, &ClusterBootstrap{}, &ClusterBootstrapList{}, &CRDPolicy{}, &CRDPolicyList{})
 
 	metav1.AddToGroupVersion(scheme, GroupVersion)
 
@@ -17,12 +17,19 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
+	"context"
 	"flag"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/fluxcd/pkg/runtime/events"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -35,6 +42,12 @@ import (
 
 	deliveryv1alpha1 "github.com/Skarlso/crd-bootstrap/api/v1alpha1"
 	"github.com/Skarlso/crd-bootstrap/internal/controller"
+	"github.com/Skarlso/crd-bootstrap/internal/metrics"
+	"github.com/Skarlso/crd-bootstrap/internal/notification"
+	"github.com/Skarlso/crd-bootstrap/internal/tracing"
+	webhookv1alpha1 "github.com/Skarlso/crd-bootstrap/internal/webhook/v1alpha1"
+	"github.com/Skarlso/crd-bootstrap/pkg/source"
+	"github.com/Skarlso/crd-bootstrap/pkg/source/auth"
 	"github.com/Skarlso/crd-bootstrap/pkg/source/configmap"
 	"github.com/Skarlso/crd-bootstrap/pkg/source/github"
 	"github.com/Skarlso/crd-bootstrap/pkg/source/gitlab"
@@ -42,6 +55,12 @@ import (
 	"github.com/Skarlso/crd-bootstrap/pkg/source/url"
 )
 
+const (
+	controllerName = "crd-bootstrap"
+	// notificationRetries is the number of times a failed notification is retried.
+	notificationRetries = 3
+)
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -61,6 +80,22 @@ func main() {
 		enableLeaderElection  bool
 		probeAddr             string
 		defaultServiceAccount string
+		enableWebhooks        bool
+		eventsAddr            string
+		tracingOptions        tracing.Options
+		notificationAddress   string
+		notificationFormat    string
+		notificationKeyFile   string
+		dependencyRequeue     time.Duration
+
+		clusterBootstrapNamespace   string
+		disableNamespacedBootstraps bool
+		bootstrapNamespaces         []string
+		noCrossNamespaceRefs        bool
+		enforceImpersonation        bool
+		versionCacheTTL             time.Duration
+		artifactCacheDir            string
+		artifactCacheSize           = resource.MustParse("1Gi")
 	)
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
@@ -69,6 +104,55 @@ func main() {
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&defaultServiceAccount, "default-service-account", "", "Default service account used for impersonation.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Enable the defaulting and validating webhooks for Bootstrap objects. "+
+			"Requires serving certificates to be mounted for the webhook server.")
+	flag.StringVar(&clusterBootstrapNamespace, "cluster-bootstrap-namespace", os.Getenv("RUNTIME_NAMESPACE"),
+		"The namespace in which Secrets, ConfigMaps and ServiceAccounts referenced by ClusterBootstraps are looked up. "+
+			"Defaults to the RUNTIME_NAMESPACE environment variable.")
+	flag.BoolVar(&disableNamespacedBootstraps, "disable-namespaced-bootstraps", false,
+		"Only reconcile ClusterBootstraps, so only cluster administrators can manage CRDs.")
+	flag.Func("bootstrap-namespaces",
+		"A comma separated list of namespaces in which Bootstraps are reconciled. All namespaces are allowed if not set.",
+		func(v string) error {
+			bootstrapNamespaces = strings.Split(v, ",")
+
+			return nil
+		})
+	flag.BoolVar(&noCrossNamespaceRefs, "no-cross-namespace-refs", false,
+		"Reject Bootstraps that reference ConfigMaps, kubeconfigs or dependencies in other namespaces.")
+	flag.BoolVar(&enforceImpersonation, "enforce-impersonation", false,
+		"Reject Bootstraps that don't impersonate a service account, either through spec.kubeConfig.serviceAccount "+
+			"or --default-service-account.")
+	flag.DurationVar(&dependencyRequeue, "requeue-dependency", 30*time.Second,
+		"The interval at which a Bootstrap waiting on its dependencies is retried.")
+	flag.DurationVar(&versionCacheTTL, "version-cache-ttl", time.Minute,
+		"How long discovered versions are shared between Bootstraps watching the same repository or chart. "+
+			"Set to 0 to disable the cache.")
+	flag.StringVar(&artifactCacheDir, "artifact-cache-dir", "",
+		"The directory in which fetched CRDs and samples are cached by source and revision. Mount a volume to keep "+
+			"them across restarts. A temporary directory is used if not set.")
+	flag.Func("artifact-cache-size",
+		"The maximum size of the artifact cache, such as 512Mi. The least recently used artifacts are removed first. "+
+			"Set to 0 to not limit the size. Defaults to 1Gi.",
+		func(v string) (err error) {
+			artifactCacheSize, err = resource.ParseQuantity(v)
+
+			return err
+		})
+	flag.StringVar(&eventsAddr, "events-addr", "",
+		"The address of an external event recorder, such as the Flux notification-controller, to forward events to.")
+
+	flag.StringVar(&tracingOptions.Endpoint, "otlp-endpoint", "",
+		"The host:port of an OTLP gRPC collector to export traces to. Tracing is disabled if not set.")
+	flag.BoolVar(&tracingOptions.Insecure, "otlp-insecure", false, "Connect to the OTLP collector without TLS.")
+
+	flag.StringVar(&notificationAddress, "notification-address", "",
+		"The URL of a webhook that is notified about upgrades and failures of every Bootstrap.")
+	flag.StringVar(&notificationFormat, "notification-format", string(notification.FormatGeneric),
+		"The payload format of the global notification webhook. One of generic, slack or msteams.")
+	flag.StringVar(&notificationKeyFile, "notification-hmac-key-file", "",
+		"The path to a file containing the key used to sign global notifications with HMAC-SHA256.")
 
 	opts := zap.Options{
 		Development: true,
@@ -97,21 +181,119 @@ func main() {
 		os.Exit(1)
 	}
 
-	c := http.DefaultClient
-	urlProvider := url.NewSource(c, mgr.GetClient(), nil)
-	githubProvider := github.NewSource(c, mgr.GetClient(), urlProvider)
-	gitlabProvider := gitlab.NewSource(c, mgr.GetClient(), githubProvider)
-	configMapProvider := configmap.NewSource(mgr.GetClient(), gitlabProvider)
+	eventRecorder, err := events.NewRecorder(mgr, ctrl.Log, eventsAddr, controllerName)
+	if err != nil {
+		setupLog.Error(err, "unable to create event recorder")
+		os.Exit(1)
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+
+	shutdownTracing, err := tracing.Setup(ctx, tracingOptions)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
+	// Sources that define their own CA bundle, client certificate or proxy get a dedicated transport instead.
+	c := &http.Client{Transport: source.NewTransport(http.DefaultTransport)}
+	// notifications are retried by the notifier, so they don't go through the retrying transport of the sources.
+	notificationClient := &http.Client{
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+		Timeout:   notification.RequestTimeout,
+	}
+	var globalNotification *notification.Target
+	if notificationAddress != "" {
+		format, err := notification.ParseFormat(notificationFormat)
+		if err != nil {
+			setupLog.Error(err, "invalid notification format")
+			os.Exit(1)
+		}
+
+		globalNotification = &notification.Target{
+			Address: notificationAddress,
+			Format:  format,
+		}
 
-	helmProvider := helm.NewSource(c, mgr.GetClient(), configMapProvider)
-	if err = (&controller.BootstrapReconciler{
+		if notificationKeyFile != "" {
+			key, err := os.ReadFile(notificationKeyFile)
+			if err != nil {
+				setupLog.Error(err, "unable to read notification hmac key")
+				os.Exit(1)
+			}
+
+			globalNotification.HMACKey = bytes.TrimSpace(key)
+		}
+	}
+
+	if artifactCacheDir == "" {
+		artifactCacheDir, err = os.MkdirTemp("", "crd-bootstrap-artifacts")
+		if err != nil {
+			setupLog.Error(err, "unable to create artifact directory")
+			os.Exit(1)
+		}
+	}
+
+	artifacts, err := source.NewArtifactStore(artifactCacheDir, artifactCacheSize.Value())
+	if err != nil {
+		setupLog.Error(err, "unable to create artifact store")
+		os.Exit(1)
+	}
+
+	versionCache := source.NewVersionCache(versionCacheTTL)
+	conditionalCache := source.NewConditionalCache(source.DefaultConditionalCacheTTL)
+	sourceProvider := source.NewRegistry().
+		Register(source.Helm, helm.NewSource(c, mgr.GetClient(), versionCache, conditionalCache)).
+		Register(source.ConfigMap, configmap.NewSource(mgr.GetClient())).
+		Register(source.GitLab, gitlab.NewSource(c, mgr.GetClient(), versionCache, conditionalCache)).
+		Register(source.GitHub, github.NewSource(c, mgr.GetClient(), versionCache, conditionalCache, auth.NewInstallationTokens())).
+		Register(source.URL, url.NewSource(c, mgr.GetClient(), conditionalCache, artifacts)).
+		WithArtifactStore(artifacts)
+
+	reconciler := &controller.BootstrapReconciler{
 		Client:                mgr.GetClient(),
 		Scheme:                mgr.GetScheme(),
-		SourceProvider:        helmProvider,
+		SourceProvider:        sourceProvider,
 		DefaultServiceAccount: defaultServiceAccount,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Bootstrap")
-		os.Exit(1)
+		EventRecorder:         eventRecorder,
+		MetricsRecorder:       metrics.MustMakeRecorder(),
+		Notifier:              notification.NewNotifier(mgr.GetClient(), notificationClient, notificationRetries, globalNotification),
+
+		DependencyRequeueInterval: dependencyRequeue,
+		AllowedNamespaces:         bootstrapNamespaces,
+		NoCrossNamespaceRefs:      noCrossNamespaceRefs,
+		EnforceImpersonation:      enforceImpersonation,
+	}
+
+	if !disableNamespacedBootstraps {
+		if err = reconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Bootstrap")
+			os.Exit(1)
+		}
+	}
+
+	if clusterBootstrapNamespace != "" {
+		if err = (&controller.ClusterBootstrapReconciler{
+			BootstrapReconciler: reconciler,
+			Namespace:           clusterBootstrapNamespace,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ClusterBootstrap")
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("ClusterBootstraps aren't reconciled because neither --cluster-bootstrap-namespace nor RUNTIME_NAMESPACE is set")
+	}
+
+	if enableWebhooks {
+		if err := webhookv1alpha1.SetupBootstrapWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Bootstrap")
+			os.Exit(1)
+		}
+
+		if err := webhookv1alpha1.SetupClusterBootstrapWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ClusterBootstrap")
+			os.Exit(1)
+		}
 	}
 	//+kubebuilder:scaffold:builder
 
@@ -127,8 +309,12 @@ func main() {
 
 	setupLog.Info("starting manager")
 
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		setupLog.Error(err, "failed to shut down tracing")
+	}
 }
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+// accessDeniedReason is the reason of the Ready condition if a Bootstrap isn't allowed to reference an object
+// or to apply CRDs without impersonation.
+const accessDeniedReason = "AccessDenied"
+
+// checkAccess returns an error if the Bootstrap references objects in other namespaces while cross-namespace
+// references are disabled, or if no service account is impersonated while impersonation is enforced.
+// ClusterBootstraps are managed by cluster administrators, so they may reference any namespace.
+func (r *BootstrapReconciler) checkAccess(obj *v1alpha1.Bootstrap) error {
+	if r.NoCrossNamespaceRefs && !isClusterBootstrap(obj) {
+		if err := checkCrossNamespaceRefs(obj); err != nil {
+			return err
+		}
+	}
+
+	if r.EnforceImpersonation {
+		return r.checkImpersonation(obj)
+	}
+
+	return nil
+}
+
+// checkImpersonation returns an error if CRDs would be applied to a cluster without impersonating a service account.
+func (r *BootstrapReconciler) checkImpersonation(obj *v1alpha1.Bootstrap) error {
+	if obj.Spec.Targets == nil {
+		if r.serviceAccountName(obj.Spec.KubeConfig) == "" {
+			return errors.New("impersonation is enforced, set spec.kubeConfig.serviceAccount or a default service account")
+		}
+
+		return nil
+	}
+
+	for i := range obj.Spec.Targets.Clusters {
+		if r.serviceAccountName(&obj.Spec.Targets.Clusters[i].KubeConfig) == "" {
+			return fmt.Errorf("impersonation is enforced, set spec.targets.clusters[%d].kubeConfig.serviceAccount or a default service account", i)
+		}
+	}
+
+	if obj.Spec.Targets.Selector != nil && r.serviceAccountName(&v1alpha1.KubeConfig{ServiceAccount: obj.Spec.Targets.ServiceAccount}) == "" {
+		return errors.New("impersonation is enforced, set spec.targets.serviceAccount or a default service account")
+	}
+
+	return nil
+}
+
+// checkCrossNamespaceRefs returns an error for the first reference to a namespace other than the Bootstrap's.
+func checkCrossNamespaceRefs(obj *v1alpha1.Bootstrap) error {
+	check := func(path, namespace string) error {
+		if namespace != "" && namespace != obj.Namespace {
+			return fmt.Errorf("cross-namespace references aren't allowed, but %s is set to %s", path, namespace)
+		}
+
+		return nil
+	}
+
+	if src := obj.Spec.Source; src != nil && src.ConfigMap != nil {
+		if err := check("spec.source.configMap.namespace", src.ConfigMap.Namespace); err != nil {
+			return err
+		}
+	}
+
+	if obj.Spec.KubeConfig != nil {
+		if err := check("spec.kubeConfig.namespace", obj.Spec.KubeConfig.Namespace); err != nil {
+			return err
+		}
+	}
+
+	if obj.Spec.Targets != nil {
+		for i, t := range obj.Spec.Targets.Clusters {
+			if err := check(fmt.Sprintf("spec.targets.clusters[%d].kubeConfig.namespace", i), t.KubeConfig.Namespace); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, dep := range obj.Spec.DependsOn {
+		if err := check(fmt.Sprintf("spec.dependsOn[%d].namespace", i), dep.Namespace); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// serviceAccountName returns the name of the service account that is impersonated to apply the CRDs with
+// the kubeconfig.
+func (r *BootstrapReconciler) serviceAccountName(kc *v1alpha1.KubeConfig) string {
+	if kc != nil && kc.ServiceAccount != "" {
+		return kc.ServiceAccount
+	}
+
+	return r.DefaultServiceAccount
+}
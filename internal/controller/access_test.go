@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+func TestCheckAccess(t *testing.T) {
+	tests := []struct {
+		name        string
+		reconciler  *BootstrapReconciler
+		spec        v1alpha1.BootstrapSpec
+		cluster     bool
+		expectedErr string
+	}{
+		{
+			name:       "cross-namespace references allowed by default",
+			reconciler: &BootstrapReconciler{},
+			spec: v1alpha1.BootstrapSpec{
+				Source: &v1alpha1.Source{ConfigMap: &v1alpha1.ConfigMap{Name: "crds", Namespace: "other"}},
+			},
+		},
+		{
+			name:       "same namespace references",
+			reconciler: &BootstrapReconciler{NoCrossNamespaceRefs: true},
+			spec: v1alpha1.BootstrapSpec{
+				Source:     &v1alpha1.Source{ConfigMap: &v1alpha1.ConfigMap{Name: "crds", Namespace: "tenant"}},
+				KubeConfig: &v1alpha1.KubeConfig{SecretRef: &meta.KubeConfigReference{}},
+				DependsOn:  []v1alpha1.Dependency{{Name: "other"}, {Name: "other", Namespace: "tenant"}},
+			},
+		},
+		{
+			name:       "configmap in other namespace",
+			reconciler: &BootstrapReconciler{NoCrossNamespaceRefs: true},
+			spec: v1alpha1.BootstrapSpec{
+				Source: &v1alpha1.Source{ConfigMap: &v1alpha1.ConfigMap{Name: "crds", Namespace: "other"}},
+			},
+			expectedErr: "cross-namespace references aren't allowed, but spec.source.configMap.namespace is set to other",
+		},
+		{
+			name:       "kubeconfig in other namespace",
+			reconciler: &BootstrapReconciler{NoCrossNamespaceRefs: true},
+			spec: v1alpha1.BootstrapSpec{
+				KubeConfig: &v1alpha1.KubeConfig{Namespace: "other"},
+			},
+			expectedErr: "cross-namespace references aren't allowed, but spec.kubeConfig.namespace is set to other",
+		},
+		{
+			name:       "dependency in other namespace",
+			reconciler: &BootstrapReconciler{NoCrossNamespaceRefs: true},
+			spec: v1alpha1.BootstrapSpec{
+				DependsOn: []v1alpha1.Dependency{{Name: "other"}, {Name: "other", Namespace: "other"}},
+			},
+			expectedErr: "cross-namespace references aren't allowed, but spec.dependsOn[1].namespace is set to other",
+		},
+		{
+			name:       "target kubeconfig in other namespace",
+			reconciler: &BootstrapReconciler{NoCrossNamespaceRefs: true},
+			spec: v1alpha1.BootstrapSpec{
+				Targets: &v1alpha1.Targets{Clusters: []v1alpha1.Target{
+					{Name: "east"},
+					{Name: "west", KubeConfig: v1alpha1.KubeConfig{Namespace: "other"}},
+				}},
+			},
+			expectedErr: "cross-namespace references aren't allowed, but spec.targets.clusters[1].kubeConfig.namespace is set to other",
+		},
+		{
+			name:       "cluster bootstrap may reference other namespaces",
+			reconciler: &BootstrapReconciler{NoCrossNamespaceRefs: true},
+			spec: v1alpha1.BootstrapSpec{
+				Source: &v1alpha1.Source{ConfigMap: &v1alpha1.ConfigMap{Name: "crds", Namespace: "other"}},
+			},
+			cluster: true,
+		},
+		{
+			name:        "impersonation enforced without service account",
+			reconciler:  &BootstrapReconciler{EnforceImpersonation: true},
+			expectedErr: "impersonation is enforced, set spec.kubeConfig.serviceAccount or a default service account",
+		},
+		{
+			name:       "impersonation enforced with service account",
+			reconciler: &BootstrapReconciler{EnforceImpersonation: true},
+			spec: v1alpha1.BootstrapSpec{
+				KubeConfig: &v1alpha1.KubeConfig{ServiceAccount: "crd-installer"},
+			},
+		},
+		{
+			name:       "impersonation enforced for target without service account",
+			reconciler: &BootstrapReconciler{EnforceImpersonation: true},
+			spec: v1alpha1.BootstrapSpec{
+				KubeConfig: &v1alpha1.KubeConfig{ServiceAccount: "crd-installer"},
+				Targets: &v1alpha1.Targets{Clusters: []v1alpha1.Target{
+					{Name: "east", KubeConfig: v1alpha1.KubeConfig{ServiceAccount: "crd-installer"}},
+					{Name: "west"},
+				}},
+			},
+			expectedErr: "impersonation is enforced, set spec.targets.clusters[1].kubeConfig.serviceAccount or a default service account",
+		},
+		{
+			name:       "impersonation enforced for selected targets without service account",
+			reconciler: &BootstrapReconciler{EnforceImpersonation: true},
+			spec: v1alpha1.BootstrapSpec{
+				Targets: &v1alpha1.Targets{Selector: &metav1.LabelSelector{}},
+			},
+			expectedErr: "impersonation is enforced, set spec.targets.serviceAccount or a default service account",
+		},
+		{
+			name:       "impersonation enforced for targets with service accounts",
+			reconciler: &BootstrapReconciler{EnforceImpersonation: true},
+			spec: v1alpha1.BootstrapSpec{
+				Targets: &v1alpha1.Targets{
+					Clusters:       []v1alpha1.Target{{Name: "east", KubeConfig: v1alpha1.KubeConfig{ServiceAccount: "crd-installer"}}},
+					Selector:       &metav1.LabelSelector{},
+					ServiceAccount: "crd-installer",
+				},
+			},
+		},
+		{
+			name:       "impersonation enforced with default service account",
+			reconciler: &BootstrapReconciler{EnforceImpersonation: true, DefaultServiceAccount: "crd-installer"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &v1alpha1.Bootstrap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "tenant"},
+				Spec:       tt.spec,
+			}
+			if tt.cluster {
+				obj = (&v1alpha1.ClusterBootstrap{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: tt.spec}).AsBootstrap("crd-bootstrap-system")
+			}
+
+			err := tt.reconciler.checkAccess(obj)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
@@ -18,7 +18,25 @@ import (
 
 // readObjects takes a path to a file that contains one or more CRDs and created a list of
 // unstructured objects out of them.
-func readObjects(manifestPath string) (_ []*unstructured.Unstructured, err error) {
+func readObjects(manifestPath string) ([]*unstructured.Unstructured, error) {
+	objects, err := readAllObjects(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	// Make sure we only returns custom resource definitions. We don't want any errand objects be applied to the cluster.
+	crds := make([]*unstructured.Unstructured, 0)
+
+	for _, obj := range objects {
+		if obj.GetKind() == "CustomResourceDefinition" {
+			crds = append(crds, obj)
+		}
+	}
+
+	return crds, nil
+}
+
+// readAllObjects reads all objects from the given file regardless of their kind.
+func readAllObjects(manifestPath string) (_ []*unstructured.Unstructured, err error) {
 	fi, err := os.Lstat(manifestPath)
 	if err != nil {
 		return nil, err
@@ -39,39 +57,27 @@ func readObjects(manifestPath string) (_ []*unstructured.Unstructured, err error
 		}
 	}()
 
-	objects, err := utils.ReadObjects(bufio.NewReader(ms))
-	if err != nil {
-		return nil, err
-	}
-	// Make sure we only returns custom resource definitions. We don't want any errand objects be applied to the cluster.
-	crds := make([]*unstructured.Unstructured, 0)
-
-	for _, obj := range objects {
-		if obj.GetKind() == "CustomResourceDefinition" {
-			crds = append(crds, obj)
-		}
-	}
-
-	return crds, nil
+	return utils.ReadObjects(bufio.NewReader(ms))
 }
 
-// NewResourceManager creates a ResourceManager for the given cluster.
+// NewResourceManager creates a ResourceManager for the cluster the Bootstrap applies CRDs to.
 func (r *BootstrapReconciler) NewResourceManager(ctx context.Context, obj *v1alpha1.Bootstrap) (*ssa.ResourceManager, error) {
+	return r.newResourceManager(ctx, obj, obj.Spec.KubeConfig)
+}
+
+// newResourceManager creates a ResourceManager for the cluster of the kubeconfig, or for the cluster the
+// controller runs in if it's nil.
+func (r *BootstrapReconciler) newResourceManager(ctx context.Context, obj *v1alpha1.Bootstrap, kc *v1alpha1.KubeConfig) (*ssa.ResourceManager, error) {
 	ownerRef := ssa.Owner{
 		Field: "delivery",
 		Group: "crd-bootstrap.delivery.crd-bootstrap",
 	}
 
-	var serviceAccountName string
-	if obj.Spec.KubeConfig != nil {
-		serviceAccountName = obj.Spec.KubeConfig.ServiceAccount
-	}
-
 	opts := []runtimeClient.ImpersonatorOption{
-		runtimeClient.WithServiceAccount(r.DefaultServiceAccount, serviceAccountName, obj.Namespace),
+		runtimeClient.WithServiceAccount(r.DefaultServiceAccount, r.serviceAccountName(kc), obj.Namespace),
 	}
-	if obj.Spec.KubeConfig != nil {
-		opts = append(opts, runtimeClient.WithKubeConfig(obj.Spec.KubeConfig.SecretRef, runtimeClient.KubeConfigOptions{}, obj.Spec.KubeConfig.Namespace, nil))
+	if kc != nil {
+		opts = append(opts, runtimeClient.WithKubeConfig(kc.SecretRef, runtimeClient.KubeConfigOptions{}, kubeConfigNamespace(obj, kc), nil))
 	}
 
 	// Configure the Kubernetes client for impersonation.
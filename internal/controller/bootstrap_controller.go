@@ -21,26 +21,37 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/fluxcd/pkg/runtime/conditions"
 	"github.com/fluxcd/pkg/runtime/patch"
+	"github.com/fluxcd/pkg/runtime/predicates"
 	"github.com/fluxcd/pkg/ssa"
-	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
-	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/yaml"
+	kuberecorder "k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+	"github.com/Skarlso/crd-bootstrap/internal/metrics"
+	"github.com/Skarlso/crd-bootstrap/internal/notification"
+	"github.com/Skarlso/crd-bootstrap/internal/tracing"
 	"github.com/Skarlso/crd-bootstrap/pkg/breaking"
 	"github.com/Skarlso/crd-bootstrap/pkg/source"
 )
@@ -57,12 +68,69 @@ type BootstrapReconciler struct {
 
 	SourceProvider        source.Contract
 	DefaultServiceAccount string
+	EventRecorder         kuberecorder.EventRecorder
+	MetricsRecorder       *metrics.Recorder
+	Notifier              *notification.Notifier
+
+	// DependencyRequeueInterval is the interval at which a Bootstrap waiting on its dependencies is retried.
+	DependencyRequeueInterval time.Duration
+
+	// NoCrossNamespaceRefs rejects Bootstraps that reference objects in other namespaces.
+	NoCrossNamespaceRefs bool
+
+	// EnforceImpersonation rejects Bootstraps that would apply CRDs without impersonating a service account.
+	EnforceImpersonation bool
+
+	// AllowedNamespaces restricts the namespaces in which Bootstraps are reconciled. All namespaces are allowed
+	// if it's empty.
+	AllowedNamespaces []string
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *BootstrapReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	ctx := context.Background()
+
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &v1alpha1.Bootstrap{}, configMapRefIndexKey, indexConfigMapRefs); err != nil {
+		return fmt.Errorf("failed to set up configmap index: %w", err)
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &v1alpha1.Bootstrap{}, secretRefIndexKey, indexSecretRefs); err != nil {
+		return fmt.Errorf("failed to set up secret index: %w", err)
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &v1alpha1.Bootstrap{}, dependsOnIndexKey, indexDependsOn); err != nil {
+		return fmt.Errorf("failed to set up dependency index: %w", err)
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.Bootstrap{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		For(&v1alpha1.Bootstrap{}, builder.WithPredicates(
+			predicate.Or(predicate.GenerationChangedPredicate{}, predicates.ReconcileRequestedPredicate{}),
+		)).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForReferencedObject(configMapRefIndexKey)),
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+		).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForReferencedObject(secretRefIndexKey)),
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+		).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForTargetSecret),
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+		).
+		Watches(
+			&v1alpha1.Bootstrap{},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForReferencedObject(dependsOnIndexKey)),
+			builder.WithPredicates(dependencyReadyPredicate()),
+		).
+		Watches(
+			&v1alpha1.CRDPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForAllBootstraps),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
 		Complete(r)
 }
 
@@ -74,11 +142,18 @@ func (r *BootstrapReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (r *BootstrapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
-	logger := log.FromContext(ctx)
+	ctx, span := tracing.Tracer().Start(ctx, "Reconcile", trace.WithAttributes(
+		attribute.String("bootstrap.name", req.Name),
+		attribute.String("bootstrap.namespace", req.Namespace),
+	))
+	defer func() {
+		tracing.End(span, err)
+	}()
 
 	obj := &v1alpha1.Bootstrap{}
 	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
@@ -89,70 +164,164 @@ func (r *BootstrapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
+	patchHelper := patch.NewSerialPatcher(obj, r.Client)
+	patchObject := func(ctx context.Context) error {
+		return patchHelper.Patch(ctx, obj)
+	}
+
+	if !r.namespaceAllowed(obj.Namespace) {
+		return r.reconcileNotAllowed(ctx, obj, patchObject)
+	}
+
+	return r.reconcile(ctx, obj, patchObject)
+}
+
+// reconcile reconciles the Bootstrap and calls patchObject to persist its finalizers and status.
+func (r *BootstrapReconciler) reconcile(ctx context.Context, obj *v1alpha1.Bootstrap, patchObject func(context.Context) error) (_ ctrl.Result, err error) {
+	logger := log.FromContext(ctx)
+	start := time.Now()
+
 	if obj.DeletionTimestamp != nil {
 		if !controllerutil.ContainsFinalizer(obj, finalizer) {
 			return ctrl.Result{}, nil
 		}
 
-		err := r.reconcileDelete(ctx, obj)
+		err := r.reconcileDelete(ctx, obj, patchObject)
 		if err != nil {
 			return ctrl.Result{}, fmt.Errorf("failed to delete bootstrap: %w", err)
 		}
 
+		r.deleteMetrics(obj)
+
 		return ctrl.Result{}, nil
 	}
 
-	logger.Info("starting reconcile loop")
+	r.recordSuspension(obj)
 
-	patchHelper := patch.NewSerialPatcher(obj, r.Client)
+	if obj.Spec.Suspend {
+		logger.Info("reconciliation is suspended for this object")
+
+		return ctrl.Result{}, nil
+	}
+
+	logger.Info("starting reconcile loop")
 
 	// AddFinalizer if not present already.
 	controllerutil.AddFinalizer(obj, finalizer)
 
 	// Always attempt to patch the object and status after each reconciliation.
 	defer func() {
-		// Patching has not been set up, or the controller errored earlier.
-		if patchHelper == nil {
-			return
-		}
-
 		obj.Status.ObservedGeneration = obj.Generation
 
+		// Record that a reconcile requested through the annotation was handled.
+		if v, ok := meta.ReconcileAnnotationValue(obj.GetAnnotations()); ok {
+			obj.Status.SetLastHandledReconcileRequest(v)
+		}
+
 		// Set status observed generation option if the object is stalled or ready.
-		perr := patchHelper.Patch(ctx, obj)
+		perr := patchObject(ctx)
 		if perr != nil {
 			err = errors.Join(err, perr)
 		}
+
+		r.recordReadiness(obj, start)
 	}()
 
-	update, revision, err := r.SourceProvider.HasUpdate(ctx, obj)
+	if err := r.checkAccess(obj); err != nil {
+		r.markFailed(ctx, obj, "", accessDeniedReason, "%s", err)
+
+		// Retrying won't help until the spec or the controller configuration changes.
+		return ctrl.Result{}, reconcile.TerminalError(err)
+	}
+
+	if err := r.checkDependencies(ctx, obj); err != nil {
+		logger.Info("dependencies aren't ready yet", "reason", err.Error())
+		conditions.MarkFalse(obj, meta.ReadyCondition, meta.DependencyNotReadyReason, "%s", err)
+		r.event(obj, "", corev1.EventTypeNormal, meta.DependencyNotReadyReason, "%s", err)
+
+		return ctrl.Result{RequeueAfter: r.dependencyRequeueInterval()}, nil
+	}
+
+	var targets []target
+
+	if obj.Spec.Targets != nil {
+		targets, err = r.resolveTargets(ctx, obj)
+		if err != nil {
+			r.markFailed(ctx, obj, "", targetResolutionFailedReason, "failed to resolve targets: %s", err)
+
+			return ctrl.Result{}, fmt.Errorf("failed to resolve targets: %w", err)
+		}
+
+		retainTargetStatuses(obj, targets)
+	} else {
+		obj.Status.Targets = nil
+		obj.Status.Stages = nil
+	}
+
+	checkStart := time.Now()
+	checkCtx, checkSpan := tracing.Tracer().Start(ctx, "HasUpdate", sourceAttributes(obj))
+	update, revision, err := r.SourceProvider.HasUpdate(checkCtx, obj)
+	tracing.End(checkSpan, err)
+	r.recordSourceRequest(obj, operationCheck, checkStart, err)
+
+	if errors.Is(err, source.ErrNoSource) || errors.Is(err, source.ErrMultipleSources) {
+		r.markFailed(ctx, obj, "", "InvalidSource", "%s", err)
+
+		// Retrying won't help until the spec is fixed, which triggers a new reconcile.
+		return ctrl.Result{}, reconcile.TerminalError(err)
+	}
+
 	if err != nil {
+		reason, retry := sourceFailure(obj, err, "VersionCheckFailed")
+		r.markFailed(ctx, obj, "", reason, "failed to check version: %s", err)
+
+		if retry > 0 {
+			return ctrl.Result{RequeueAfter: retry}, nil
+		}
+
 		return ctrl.Result{}, fmt.Errorf("failed to check version: %w", err)
 	}
 
-	if !update {
+	// A revision that is up to date is still rolled out to targets that don't have it yet.
+	if !update && (targets == nil || revision == "" || !targetsOutdated(obj, targets, revision)) {
 		logger.Info("no update was required...")
 		conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "Successfully applied crd(s)")
 
 		return ctrl.Result{RequeueAfter: obj.GetRequeueAfter()}, nil
 	}
 
+	// A staged rollout is reconciled again while it progresses, the update is only reported once.
+	if update && revision != obj.Status.LastAttemptedRevision {
+		r.event(obj, revision, corev1.EventTypeNormal, "UpdateDetected", "new revision %s detected, last applied revision is '%s'",
+			revision, obj.Status.LastAppliedRevision)
+	}
+
 	logger.Info("fetching CRD content")
 
 	obj.Status.LastAttemptedRevision = revision
 
 	temp, err := os.MkdirTemp("", "crd")
 	if err != nil {
-		conditions.MarkFalse(obj, meta.ReadyCondition, "TempFolderFailedToCreate", "failed to create temp directory: %s", err)
+		r.markFailed(ctx, obj, revision, "TempFolderFailedToCreate", "failed to create temp directory: %s", err)
 
 		return ctrl.Result{}, fmt.Errorf("failed to create temp directory: %w", err)
 	}
 
 	// should probably return a file system / single YAML. Because they can be super large, it's
 	// not vise to store it in memory as a buffer.
-	location, err := r.SourceProvider.FetchCRD(ctx, temp, obj, revision)
+	fetchStart := time.Now()
+	fetchCtx, fetchSpan := tracing.Tracer().Start(ctx, "FetchCRD", sourceAttributes(obj), trace.WithAttributes(attribute.String("revision", revision)))
+	location, err := r.SourceProvider.FetchCRD(fetchCtx, temp, obj, revision)
+	tracing.End(fetchSpan, err)
+	r.recordSourceRequest(obj, operationFetch, fetchStart, err)
+
 	if err != nil {
-		conditions.MarkFalse(obj, meta.ReadyCondition, "CRDFetchFailed", "failed to fetch source: %s", err)
+		reason, retry := sourceFailure(obj, err, "CRDFetchFailed")
+		r.markFailed(ctx, obj, revision, reason, "failed to fetch source: %s", err)
+
+		if retry > 0 {
+			return ctrl.Result{RequeueAfter: retry}, nil
+		}
 
 		return ctrl.Result{}, fmt.Errorf("failed to fetch source: %w", err)
 	}
@@ -164,199 +333,318 @@ func (r *BootstrapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 	}()
 
-	sm, err := r.NewResourceManager(ctx, obj)
+	objects, err := readObjects(location)
 	if err != nil {
-		conditions.MarkFalse(obj, meta.ReadyCondition, "ResourceManagerCreateFailed", "failed to create resource manager: %s", err)
+		r.markFailed(ctx, obj, revision, "ReadingObjectsToApplyFailed", "failed to construct objects to apply: %s", err)
 
-		return ctrl.Result{}, fmt.Errorf("failed to create resource manager: %w", err)
+		return ctrl.Result{}, fmt.Errorf("failed to construct objects to apply: %w", err)
 	}
 
-	objects, err := readObjects(location)
+	violations, err := r.checkPolicies(ctx, obj, objects)
 	if err != nil {
-		conditions.MarkFalse(obj, meta.ReadyCondition, "ReadingObjectsToApplyFailed", "failed to construct objects to apply: %s", err)
+		r.markFailed(ctx, obj, revision, "PolicyCheckFailed", "failed to check CRD policies: %s", err)
 
-		return ctrl.Result{}, fmt.Errorf("failed to construct objects to apply: %w", err)
+		return ctrl.Result{}, fmt.Errorf("failed to check CRD policies: %w", err)
 	}
 
+	if len(violations) > 0 {
+		message := strings.Join(violations, "; ")
+		conditions.MarkFalse(obj, policyCompliantCondition, policyViolationReason, "%s", message)
+		r.markFailed(ctx, obj, revision, policyViolationReason, "CRD policies violated: %s", message)
+
+		return ctrl.Result{}, fmt.Errorf("CRD policies violated: %s", message)
+	}
+
+	conditions.MarkTrue(obj, policyCompliantCondition, meta.SucceededReason, "All CRDs are allowed by the CRD policies")
+
 	applied := obj.Status.LastAppliedCRDNames
 	if applied == nil {
 		applied = make(map[string]int)
 	}
 
 	for _, o := range objects {
-		o.SetLabels(map[string]string{
-			v1alpha1.BootstrapOwnerLabelKey: obj.GetName(),
-		})
+		o.SetLabels(ownerLabels(obj))
 
 		applied[o.GetName()]++
 	}
 
-	breakingChanges, berr := r.detectBreakingChanges(ctx, objects)
-	if berr != nil {
-		conditions.MarkFalse(obj, meta.ReadyCondition, "BreakingChangeDetectionFailed", "failed to detect breaking changes: %s", berr)
-
-		return ctrl.Result{}, fmt.Errorf("failed to detect breaking changes: %w", berr)
-	}
-
-	obj.Status.BreakingChanges = breakingChanges
+	validateCtx, validateSpan := tracing.Tracer().Start(ctx, "ValidateObjects")
+	err = r.validateObjects(validateCtx, obj, objects, temp, revision)
+	tracing.End(validateSpan, err)
 
-	if len(breakingChanges) > 0 {
-		if !obj.Spec.IgnoreBreakingChanges {
-			conditions.MarkFalse(obj, meta.ReadyCondition, "BreakingChangeDetected", "breaking schema changes detected")
-
-			return ctrl.Result{}, fmt.Errorf("breaking schema changes detected: %v", breakingChanges)
+	if err != nil {
+		if r.MetricsRecorder != nil {
+			r.MetricsRecorder.RecordValidationFailure(obj.Name, objectNamespace(obj))
 		}
 
-		logger.Info("breaking changes detected but ignoreBreakingChanges is set, proceeding", "breakingChanges", breakingChanges)
-	}
-
-	if err := r.validateObjects(ctx, obj, objects); err != nil {
 		if !obj.Spec.ContinueOnValidationError {
-			conditions.MarkFalse(obj, meta.ReadyCondition, "CRDValidationFailed", "validation failed to on the crd template: %s", err)
+			r.markFailed(ctx, obj, revision, "CRDValidationFailed", "validation failed to on the crd template: %s", err)
 			logger.Error(err, "validation failed to the CRD for the provided template")
 
 			return ctrl.Result{}, err
 		}
 
 		logger.Error(err, "validation failed for the CRD, but continue is set so we'll ignore this error")
+		r.event(obj, revision, corev1.EventTypeWarning, "CRDValidationFailed",
+			"validation failed on the crd template, continuing because continueOnValidationError is set: %s", err)
 	}
 
-	if _, err := sm.ApplyAllStaged(ctx, objects, ssa.DefaultApplyOptions()); err != nil {
-		err := fmt.Errorf("failed to apply manifests: %w", err)
-		conditions.MarkFalse(obj, meta.ReadyCondition, "ApplyingCRDSFailed", "failed to apply all stages: %s", err)
+	if targets != nil {
+		requeue, complete, err := r.reconcileTargets(ctx, obj, targets, objects, revision)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
 
-		return ctrl.Result{}, fmt.Errorf("failed to apply all stages: %w", err)
-	}
+		if !complete {
+			return ctrl.Result{RequeueAfter: requeue}, nil
+		}
+	} else {
+		sm, err := r.NewResourceManager(ctx, obj)
+		if err != nil {
+			r.markFailed(ctx, obj, revision, "ResourceManagerCreateFailed", "failed to create resource manager: %s", err)
+
+			return ctrl.Result{}, fmt.Errorf("failed to create resource manager: %w", err)
+		}
 
-	if err = sm.Wait(objects, ssa.DefaultWaitOptions()); err != nil {
-		err := fmt.Errorf("failed to wait for objects to be ready: %w", err)
-		conditions.MarkFalse(obj, meta.ReadyCondition, "WaitingOnObjectsFailed", "failed to wait for applied objects: %s", err)
+		breakingChanges, reason, err := r.applyObjects(ctx, obj, sm, objects, revision)
+		obj.Status.BreakingChanges = breakingChanges
+
+		if err != nil {
+			r.markFailed(ctx, obj, revision, reason, "%s", err)
 
-		return ctrl.Result{}, fmt.Errorf("failed to wait for applied objects: %w", err)
+			return ctrl.Result{}, err
+		}
 	}
 
+	previousRevision := obj.Status.LastAppliedRevision
 	obj.Status.LastAppliedCRDNames = applied
 	obj.Status.LastAppliedRevision = revision
 
 	conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "Successfully applied crd(s)")
+	if r.MetricsRecorder != nil {
+		t, _ := source.TypeOf(obj.Spec.Source)
+		r.MetricsRecorder.RecordApplied(obj.Name, objectNamespace(obj), string(t), revision, len(applied), time.Now())
+	}
+
+	r.event(obj, revision, corev1.EventTypeNormal, "CRDsApplied", "applied revision %s: %s", revision, strings.Join(objectNames(objects), ", "))
+	r.notify(ctx, obj, notification.Event{
+		Reason:      notification.ReasonUpgraded,
+		OldRevision: previousRevision,
+		NewRevision: revision,
+		CRDs:        objectNames(objects),
+	})
 
 	logger.Info("all done")
 
 	return ctrl.Result{RequeueAfter: obj.GetRequeueAfter()}, nil
 }
 
-func (r *BootstrapReconciler) reconcileDelete(ctx context.Context, obj *v1alpha1.Bootstrap) error {
-	patchHelper := patch.NewSerialPatcher(obj, r.Client)
-
+func (r *BootstrapReconciler) reconcileDelete(ctx context.Context, obj *v1alpha1.Bootstrap, patchObject func(context.Context) error) error {
 	// don't delete anything if prune is not set.
 	if !obj.Spec.Prune {
 		controllerutil.RemoveFinalizer(obj, finalizer)
 
-		return patchHelper.Patch(ctx, obj)
+		return patchObject(ctx)
 	}
 
+	log.FromContext(ctx).Info("cleaning owned CRDS...")
+
+	if obj.Spec.Targets != nil {
+		if err := r.pruneTargets(ctx, obj); err != nil {
+			return err
+		}
+	} else {
+		pruned, err := r.pruneCRDs(ctx, obj, r.Client)
+		if err != nil {
+			return err
+		}
+
+		if len(pruned) > 0 {
+			r.event(obj, obj.Status.LastAppliedRevision, corev1.EventTypeNormal, "CRDsPruned", "pruned CRDs: %s", strings.Join(pruned, ", "))
+		}
+	}
+
+	controllerutil.RemoveFinalizer(obj, finalizer)
+
+	return patchObject(ctx)
+}
+
+// pruneCRDs deletes the CRDs labelled as applied by the Bootstrap from the cluster of the client and returns
+// their names.
+func (r *BootstrapReconciler) pruneCRDs(ctx context.Context, obj *v1alpha1.Bootstrap, c client.Client) ([]string, error) {
 	logger := log.FromContext(ctx)
-	logger.Info("cleaning owned CRDS...")
 
 	crds := &v1.CustomResourceDefinitionList{}
 
-	err := r.List(ctx, crds, client.MatchingLabels(map[string]string{
-		v1alpha1.BootstrapOwnerLabelKey: obj.GetName(),
-	}))
+	err := c.List(ctx, crds, client.MatchingLabels(ownerLabels(obj)))
 	if err != nil {
-		return fmt.Errorf("failed to list owned CRDS: %w", err)
+		return nil, fmt.Errorf("failed to list owned CRDS: %w", err)
 	}
 
 	logger.Info("found number of crds to clean", "number", len(crds.Items))
 
+	pruned := make([]string, 0, len(crds.Items))
+
 	for _, item := range crds.Items {
 		logger.V(v1alpha1.LogLevelDebug).Info("removed CRD", "crd", item.GetName())
 
-		if err := r.Delete(ctx, &item); err != nil {
-			return fmt.Errorf("failed to delete object with name %s: %w", item.GetName(), err)
+		if err := c.Delete(ctx, &item); err != nil {
+			r.event(obj, obj.Status.LastAppliedRevision, corev1.EventTypeWarning, "PruneFailed", "failed to delete CRD %s: %s", item.GetName(), err)
+
+			return nil, fmt.Errorf("failed to delete object with name %s: %w", item.GetName(), err)
 		}
-	}
 
-	controllerutil.RemoveFinalizer(obj, finalizer)
+		pruned = append(pruned, item.GetName())
+	}
 
-	return patchHelper.Patch(ctx, obj)
+	return pruned, nil
 }
 
-func (r *BootstrapReconciler) validateObjects(ctx context.Context, obj *v1alpha1.Bootstrap, objects []*unstructured.Unstructured) error {
-	// bail early if there are no templates.
-	if obj.Spec.Template == nil {
-		return nil
+// objectNames returns the sorted names of the objects.
+func objectNames(objects []*unstructured.Unstructured) []string {
+	names := make([]string, 0, len(objects))
+	for _, o := range objects {
+		names = append(names, o.GetName())
 	}
 
+	slices.Sort(names)
+
+	return names
+}
+
+// applyObjects detects breaking changes against the CRDs in the cluster of the resource manager, applies the
+// objects and waits for them to be ready. It returns the detected breaking changes and, if it fails, the reason
+// of the failure.
+func (r *BootstrapReconciler) applyObjects(ctx context.Context, obj *v1alpha1.Bootstrap, sm *ssa.ResourceManager, objects []*unstructured.Unstructured, revision string) ([]string, string, error) {
 	logger := log.FromContext(ctx)
 
-	for _, o := range objects {
-		logger.Info("validating the following object against set template data", "name", o.GetName())
-		// Create a CRD out of the content.
-		content, err := o.MarshalJSON()
-		if err != nil {
-			return err
-		}
+	breakingChanges, err := detectBreakingChanges(ctx, sm.Client(), objects)
+	if err != nil {
+		return nil, "BreakingChangeDetectionFailed", fmt.Errorf("failed to detect breaking changes: %w", err)
+	}
 
-		crd := &apiextensions.CustomResourceDefinition{}
-		if err := yaml.Unmarshal(content, crd); err != nil {
-			return errors.New("failed to unmarshal into custom resource definition")
+	if len(breakingChanges) > 0 {
+		if r.MetricsRecorder != nil {
+			r.MetricsRecorder.RecordBreakingChanges(obj.Name, objectNamespace(obj), !obj.Spec.IgnoreBreakingChanges)
 		}
 
-		// Add checking out the api version from the provided template and only eval against that.
-		for _, v := range crd.Spec.Versions {
-			eval, _, err := validation.NewSchemaValidator(v.Schema.OpenAPIV3Schema)
-			if err != nil {
-				return err
-			}
-
-			if v, ok := obj.Spec.Template[crd.Spec.Names.Kind]; ok {
-				err := eval.Validate(v).AsError()
-				if err != nil {
-					return fmt.Errorf("failed to validate kind %s: %w", crd.Spec.Names.Kind, err)
-				}
-			}
+		if !obj.Spec.IgnoreBreakingChanges {
+			return breakingChanges, breakingChangeDetectedReason, fmt.Errorf("breaking schema changes detected: %s", strings.Join(breakingChanges, "; "))
 		}
+
+		logger.Info("breaking changes detected but ignoreBreakingChanges is set, proceeding", "breakingChanges", breakingChanges)
+		r.event(obj, revision, corev1.EventTypeWarning, "BreakingChangeIgnored",
+			"breaking schema changes ignored because ignoreBreakingChanges is set: %s", strings.Join(breakingChanges, "; "))
 	}
 
-	return nil
+	applyCtx, applySpan := tracing.Tracer().Start(ctx, "ApplyAllStaged")
+	_, err = sm.ApplyAllStaged(applyCtx, objects, ssa.DefaultApplyOptions())
+	tracing.End(applySpan, err)
+
+	if err != nil {
+		return breakingChanges, "ApplyingCRDSFailed", fmt.Errorf("failed to apply all stages: %w", err)
+	}
+
+	_, waitSpan := tracing.Tracer().Start(ctx, "Wait")
+	err = sm.Wait(objects, ssa.DefaultWaitOptions())
+	tracing.End(waitSpan, err)
+
+	if err != nil {
+		return breakingChanges, "WaitingOnObjectsFailed", fmt.Errorf("failed to wait for applied objects: %w", err)
+	}
+
+	return breakingChanges, "", nil
 }
 
-func (r *BootstrapReconciler) detectBreakingChanges(ctx context.Context, objects []*unstructured.Unstructured) ([]string, error) {
-	logger := log.FromContext(ctx)
+// detectBreakingChanges compares the objects to the CRDs in the cluster of the client.
+func detectBreakingChanges(ctx context.Context, c client.Client, objects []*unstructured.Unstructured) ([]string, error) {
 	var allBreaking []string
 
 	for _, o := range objects {
-		content, err := o.MarshalJSON()
+		changes, err := detectBreakingChangesForObject(ctx, c, o)
 		if err != nil {
-			return nil, fmt.Errorf("marshaling object %s: %w", o.GetName(), err)
+			return nil, err
 		}
 
-		newCRD := &v1.CustomResourceDefinition{}
-		if err := yaml.Unmarshal(content, newCRD); err != nil {
-			return nil, fmt.Errorf("unmarshaling CRD %s: %w", o.GetName(), err)
-		}
+		allBreaking = append(allBreaking, changes...)
+	}
 
-		oldCRD := &v1.CustomResourceDefinition{}
+	return allBreaking, nil
+}
 
-		err = r.Get(ctx, client.ObjectKeyFromObject(newCRD), oldCRD)
-		if err != nil {
-			if apierrors.IsNotFound(err) {
-				logger.V(v1alpha1.LogLevelDebug).Info("CRD not yet installed, skipping breaking change check", "crd", o.GetName())
+func detectBreakingChangesForObject(ctx context.Context, c client.Client, o *unstructured.Unstructured) (_ []string, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "DetectBreakingChanges", trace.WithAttributes(attribute.String("crd", o.GetName())))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	logger := log.FromContext(ctx)
+
+	content, err := o.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("marshaling object %s: %w", o.GetName(), err)
+	}
 
-				continue
-			}
+	newCRD := &v1.CustomResourceDefinition{}
+	if err := yaml.Unmarshal(content, newCRD); err != nil {
+		return nil, fmt.Errorf("unmarshaling CRD %s: %w", o.GetName(), err)
+	}
+
+	oldCRD := &v1.CustomResourceDefinition{}
+
+	err = c.Get(ctx, client.ObjectKeyFromObject(newCRD), oldCRD)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.V(v1alpha1.LogLevelDebug).Info("CRD not yet installed, skipping breaking change check", "crd", o.GetName())
 
-			return nil, fmt.Errorf("fetching existing CRD %s: %w", o.GetName(), err)
+			return nil, nil
 		}
 
-		changes, err := breaking.DetectBreakingChanges(oldCRD, newCRD)
-		if err != nil {
-			return nil, fmt.Errorf("detecting breaking changes for %s: %w", o.GetName(), err)
+		return nil, fmt.Errorf("fetching existing CRD %s: %w", o.GetName(), err)
+	}
+
+	changes, err := breaking.DetectBreakingChanges(oldCRD, newCRD)
+	if err != nil {
+		return nil, fmt.Errorf("detecting breaking changes for %s: %w", o.GetName(), err)
+	}
+
+	return changes, nil
+}
+
+// namespaceAllowed returns whether Bootstraps in the namespace may be reconciled.
+func (r *BootstrapReconciler) namespaceAllowed(namespace string) bool {
+	return len(r.AllowedNamespaces) == 0 || slices.Contains(r.AllowedNamespaces, namespace)
+}
+
+// reconcileNotAllowed marks a Bootstrap in a namespace that isn't allowed to manage CRDs as failed. Deleting it
+// only removes the finalizer, since the CRDs labelled with its name might not have been applied by it.
+func (r *BootstrapReconciler) reconcileNotAllowed(ctx context.Context, obj *v1alpha1.Bootstrap, patchObject func(context.Context) error) (ctrl.Result, error) {
+	if obj.DeletionTimestamp != nil {
+		if !controllerutil.RemoveFinalizer(obj, finalizer) {
+			return ctrl.Result{}, nil
 		}
 
-		allBreaking = append(allBreaking, changes...)
+		return ctrl.Result{}, patchObject(ctx)
 	}
 
-	return allBreaking, nil
+	err := fmt.Errorf("bootstraps aren't allowed in namespace %s", obj.Namespace)
+
+	obj.Status.ObservedGeneration = obj.Generation
+	r.markFailed(ctx, obj, "", "NamespaceNotAllowed", "%s", err)
+
+	if perr := patchObject(ctx); perr != nil {
+		return ctrl.Result{}, errors.Join(err, perr)
+	}
+
+	// Retrying won't help until the controller is configured to allow the namespace.
+	return ctrl.Result{}, reconcile.TerminalError(err)
+}
+
+// ownerLabels returns the labels marking CRDs as applied by the Bootstrap.
+func ownerLabels(obj *v1alpha1.Bootstrap) map[string]string {
+	if isClusterBootstrap(obj) {
+		return map[string]string{v1alpha1.ClusterBootstrapOwnerLabelKey: obj.GetName()}
+	}
+
+	return map[string]string{v1alpha1.BootstrapOwnerLabelKey: obj.GetName()}
 }
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+// fakeSource reports that the last applied revision is up to date and records the namespace it was called for.
+type fakeSource struct {
+	checked   int
+	namespace string
+}
+
+func (f *fakeSource) FetchCRD(_ context.Context, _ string, _ *v1alpha1.Bootstrap, _ string) (string, error) {
+	return "", nil
+}
+
+func (f *fakeSource) HasUpdate(_ context.Context, obj *v1alpha1.Bootstrap) (bool, string, error) {
+	f.checked++
+	f.namespace = obj.Namespace
+
+	return false, obj.Status.LastAppliedRevision, nil
+}
+
+func newTestReconciler(t *testing.T, obj client.Object) (*BootstrapReconciler, *fakeSource) {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	src := &fakeSource{}
+
+	return &BootstrapReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(obj).
+			WithStatusSubresource(&v1alpha1.Bootstrap{}, &v1alpha1.ClusterBootstrap{}).
+			Build(),
+		SourceProvider: src,
+	}, src
+}
+
+func TestReconcileSuspended(t *testing.T) {
+	obj := &v1alpha1.Bootstrap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: v1alpha1.BootstrapSpec{
+			Interval: metav1.Duration{Duration: time.Minute},
+			Suspend:  true,
+		},
+	}
+
+	r, src := newTestReconciler(t, obj)
+
+	result, err := r.Reconcile(t.Context(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{}, result)
+	assert.Equal(t, 0, src.checked)
+}
+
+func TestReconcileHandlesRequest(t *testing.T) {
+	obj := &v1alpha1.Bootstrap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Namespace:   "default",
+			Annotations: map[string]string{meta.ReconcileRequestAnnotation: "now"},
+		},
+		Spec: v1alpha1.BootstrapSpec{
+			Interval: metav1.Duration{Duration: time.Minute},
+		},
+	}
+
+	r, src := newTestReconciler(t, obj)
+
+	result, err := r.Reconcile(t.Context(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{RequeueAfter: time.Minute}, result)
+	assert.Equal(t, 1, src.checked)
+
+	updated := &v1alpha1.Bootstrap{}
+	require.NoError(t, r.Get(t.Context(), client.ObjectKeyFromObject(obj), updated))
+	assert.Equal(t, "now", updated.Status.GetLastHandledReconcileRequest())
+}
+
+func TestReconcileNamespaceNotAllowed(t *testing.T) {
+	obj := &v1alpha1.Bootstrap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "tenant"},
+		Spec: v1alpha1.BootstrapSpec{
+			Interval: metav1.Duration{Duration: time.Minute},
+		},
+	}
+
+	r, src := newTestReconciler(t, obj)
+	r.AllowedNamespaces = []string{"crd-bootstrap-system"}
+
+	_, err := r.Reconcile(t.Context(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+	require.ErrorIs(t, err, reconcile.TerminalError(nil))
+	assert.Equal(t, 0, src.checked)
+
+	updated := &v1alpha1.Bootstrap{}
+	require.NoError(t, r.Get(t.Context(), client.ObjectKeyFromObject(obj), updated))
+
+	condition := conditions.Get(updated, meta.ReadyCondition)
+	require.NotNil(t, condition)
+	assert.Equal(t, "NamespaceNotAllowed", condition.Reason)
+	assert.Equal(t, "bootstraps aren't allowed in namespace tenant", condition.Message)
+	assert.Empty(t, updated.Finalizers)
+}
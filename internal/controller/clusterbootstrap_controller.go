@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fluxcd/pkg/runtime/patch"
+	"github.com/fluxcd/pkg/runtime/predicates"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+	"github.com/Skarlso/crd-bootstrap/internal/tracing"
+)
+
+// ClusterBootstrapReconciler reconciles a ClusterBootstrap object. It runs the reconcile of the
+// BootstrapReconciler on a Bootstrap created from the ClusterBootstrap.
+type ClusterBootstrapReconciler struct {
+	*BootstrapReconciler
+
+	// Namespace is the namespace in which Secrets, ConfigMaps and ServiceAccounts referenced by
+	// ClusterBootstraps are looked up.
+	Namespace string
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterBootstrapReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	ctx := context.Background()
+
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &v1alpha1.ClusterBootstrap{}, configMapRefIndexKey, r.index(indexConfigMapRefs)); err != nil {
+		return fmt.Errorf("failed to set up configmap index: %w", err)
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &v1alpha1.ClusterBootstrap{}, secretRefIndexKey, r.index(indexSecretRefs)); err != nil {
+		return fmt.Errorf("failed to set up secret index: %w", err)
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &v1alpha1.ClusterBootstrap{}, dependsOnIndexKey, r.index(indexDependsOn)); err != nil {
+		return fmt.Errorf("failed to set up dependency index: %w", err)
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ClusterBootstrap{}, builder.WithPredicates(
+			predicate.Or(predicate.GenerationChangedPredicate{}, predicates.ReconcileRequestedPredicate{}),
+		)).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForReferencingClusterBootstraps(configMapRefIndexKey)),
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+		).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForReferencingClusterBootstraps(secretRefIndexKey)),
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+		).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForTargetSecret),
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+		).
+		Watches(
+			&v1alpha1.ClusterBootstrap{},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForReferencingClusterBootstraps(dependsOnIndexKey)),
+			builder.WithPredicates(dependencyReadyPredicate()),
+		).
+		Watches(
+			&v1alpha1.CRDPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForAllClusterBootstraps),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Complete(r)
+}
+
+//+kubebuilder:rbac:groups=delivery.crd-bootstrap,resources=clusterbootstraps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=delivery.crd-bootstrap,resources=clusterbootstraps/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=delivery.crd-bootstrap,resources=clusterbootstraps/finalizers,verbs=update
+
+// Reconcile reconciles the Bootstrap created from the ClusterBootstrap and writes its finalizers and status
+// back to the ClusterBootstrap.
+func (r *ClusterBootstrapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "Reconcile", trace.WithAttributes(
+		attribute.String("clusterbootstrap.name", req.Name),
+	))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	obj := &v1alpha1.ClusterBootstrap{}
+	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	patchHelper := patch.NewSerialPatcher(obj, r.Client)
+	bootstrap := obj.AsBootstrap(r.Namespace)
+
+	return r.reconcile(ctx, bootstrap, func(ctx context.Context) error {
+		obj.Finalizers = bootstrap.Finalizers
+		obj.Status = bootstrap.Status
+
+		return patchHelper.Patch(ctx, obj)
+	})
+}
+
+// index returns an indexer that applies the Bootstrap indexer to the Bootstrap created from a ClusterBootstrap.
+func (r *ClusterBootstrapReconciler) index(indexer client.IndexerFunc) client.IndexerFunc {
+	return func(o client.Object) []string {
+		obj, ok := o.(*v1alpha1.ClusterBootstrap)
+		if !ok {
+			return nil
+		}
+
+		return indexer(obj.AsBootstrap(r.Namespace))
+	}
+}
+
+// requestsForReferencingClusterBootstraps returns a map function that enqueues every ClusterBootstrap that
+// references the changed object through the given index.
+func (r *ClusterBootstrapReconciler) requestsForReferencingClusterBootstraps(indexKey string) func(ctx context.Context, o client.Object) []reconcile.Request {
+	return func(ctx context.Context, o client.Object) []reconcile.Request {
+		logger := log.FromContext(ctx)
+
+		list := &v1alpha1.ClusterBootstrapList{}
+		if err := r.List(ctx, list, client.MatchingFields{indexKey: client.ObjectKeyFromObject(o).String()}); err != nil {
+			logger.Error(err, "failed to list cluster bootstraps referencing object", "name", o.GetName(), "namespace", o.GetNamespace())
+
+			return nil
+		}
+
+		requests := make([]reconcile.Request, 0, len(list.Items))
+		for _, item := range list.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+		}
+
+		return requests
+	}
+}
+
+// requestsForAllClusterBootstraps enqueues every ClusterBootstrap, so ClusterBootstraps blocked by a policy are retried when
+// policies change.
+func (r *ClusterBootstrapReconciler) requestsForAllClusterBootstraps(ctx context.Context, _ client.Object) []reconcile.Request {
+	list := &v1alpha1.ClusterBootstrapList{}
+	if err := r.List(ctx, list); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list cluster bootstraps")
+
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, item := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+	}
+
+	return requests
+}
+
+// requestsForTargetSecret enqueues every ClusterBootstrap that selects the changed Secret as a target.
+func (r *ClusterBootstrapReconciler) requestsForTargetSecret(ctx context.Context, o client.Object) []reconcile.Request {
+	if o.GetNamespace() != r.Namespace {
+		return nil
+	}
+
+	list := &v1alpha1.ClusterBootstrapList{}
+	if err := r.List(ctx, list); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list cluster bootstraps")
+
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for _, item := range list.Items {
+		if selectsTargetSecret(item.AsBootstrap(r.Namespace), o) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+		}
+	}
+
+	return requests
+}
+
+// isClusterBootstrap returns whether the Bootstrap was created from a ClusterBootstrap.
+func isClusterBootstrap(obj *v1alpha1.Bootstrap) bool {
+	return obj.Kind == v1alpha1.ClusterBootstrapKind
+}
+
+// objectKind returns the kind of the object the Bootstrap was read from.
+func objectKind(obj *v1alpha1.Bootstrap) string {
+	if isClusterBootstrap(obj) {
+		return v1alpha1.ClusterBootstrapKind
+	}
+
+	return "Bootstrap"
+}
+
+// objectNamespace returns the namespace of the object the Bootstrap was read from, which is empty for
+// a ClusterBootstrap.
+func objectNamespace(obj *v1alpha1.Bootstrap) string {
+	if isClusterBootstrap(obj) {
+		return ""
+	}
+
+	return obj.Namespace
+}
+
+// eventObject returns the object events about the Bootstrap are recorded for.
+func eventObject(obj *v1alpha1.Bootstrap) runtime.Object {
+	if !isClusterBootstrap(obj) {
+		return obj
+	}
+
+	cluster := &v1alpha1.ClusterBootstrap{TypeMeta: obj.TypeMeta}
+	cluster.Name = obj.Name
+	cluster.UID = obj.UID
+	cluster.ResourceVersion = obj.ResourceVersion
+
+	return cluster
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+func TestClusterBootstrapReconcile(t *testing.T) {
+	obj := &v1alpha1.ClusterBootstrap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Generation: 1},
+		Spec: v1alpha1.BootstrapSpec{
+			Interval: metav1.Duration{Duration: time.Minute},
+		},
+	}
+
+	bootstrapReconciler, src := newTestReconciler(t, obj)
+	r := &ClusterBootstrapReconciler{BootstrapReconciler: bootstrapReconciler, Namespace: "crd-bootstrap-system"}
+
+	result, err := r.Reconcile(t.Context(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{RequeueAfter: time.Minute}, result)
+	assert.Equal(t, "crd-bootstrap-system", src.namespace)
+
+	updated := &v1alpha1.ClusterBootstrap{}
+	require.NoError(t, r.Get(t.Context(), client.ObjectKeyFromObject(obj), updated))
+	assert.Equal(t, []string{finalizer}, updated.Finalizers)
+	assert.Equal(t, int64(1), updated.Status.ObservedGeneration)
+	assert.True(t, conditions.IsReady(updated))
+}
+
+func TestClusterBootstrapEvents(t *testing.T) {
+	recorder := record.NewFakeRecorder(1)
+	r := &BootstrapReconciler{EventRecorder: recorder}
+
+	obj := (&v1alpha1.ClusterBootstrap{ObjectMeta: metav1.ObjectMeta{Name: "test"}}).AsBootstrap("crd-bootstrap-system")
+	r.markFailed(t.Context(), obj, "", "CRDFetchFailed", "failed to fetch source")
+
+	involved, ok := eventObject(obj).(*v1alpha1.ClusterBootstrap)
+	require.True(t, ok)
+	assert.Equal(t, v1alpha1.ClusterBootstrapKind, involved.Kind)
+	assert.Empty(t, involved.Namespace)
+
+	assert.Equal(t, "Warning CRDFetchFailed failed to fetch source", <-recorder.Events)
+	assert.Equal(t, meta.ReadyCondition, obj.Status.Conditions[0].Type)
+	assert.Equal(t, map[string]string{v1alpha1.ClusterBootstrapOwnerLabelKey: "test"}, ownerLabels(obj))
+	assert.Empty(t, objectNamespace(obj))
+}
@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+const (
+	// dependsOnIndexKey indexes Bootstraps by the namespace/name of every Bootstrap they depend on.
+	dependsOnIndexKey = ".spec.dependsOn"
+
+	// defaultDependencyRequeueInterval is used if the reconciler doesn't define a requeue interval for
+	// Bootstraps waiting on their dependencies.
+	defaultDependencyRequeueInterval = 30 * time.Second
+)
+
+// dependencyKey returns the namespace/name of the dependency. ClusterBootstraps depend on other
+// ClusterBootstraps, so their dependencies don't have a namespace.
+func dependencyKey(obj *v1alpha1.Bootstrap, dep v1alpha1.Dependency) types.NamespacedName {
+	if isClusterBootstrap(obj) {
+		return types.NamespacedName{Name: dep.Name}
+	}
+
+	namespace := dep.Namespace
+	if namespace == "" {
+		namespace = obj.Namespace
+	}
+
+	return types.NamespacedName{Namespace: namespace, Name: dep.Name}
+}
+
+// indexDependsOn returns the Bootstraps a Bootstrap depends on.
+func indexDependsOn(o client.Object) []string {
+	obj, ok := o.(*v1alpha1.Bootstrap)
+	if !ok {
+		return nil
+	}
+
+	refs := make([]string, 0, len(obj.Spec.DependsOn))
+	for _, dep := range obj.Spec.DependsOn {
+		refs = append(refs, dependencyKey(obj, dep).String())
+	}
+
+	return refs
+}
+
+// checkDependencies returns an error describing the first dependency that isn't ready yet. A dependency is
+// ready if its Ready condition is true for its current generation and it applied at least the minimum revision.
+func (r *BootstrapReconciler) checkDependencies(ctx context.Context, obj *v1alpha1.Bootstrap) error {
+	for _, dep := range obj.Spec.DependsOn {
+		key := dependencyKey(obj, dep)
+
+		dependency, err := r.getDependency(ctx, obj, key)
+		if err != nil {
+			return err
+		}
+
+		if dependency.Generation != dependency.Status.ObservedGeneration || !conditions.IsReady(dependency) {
+			return fmt.Errorf("dependency %s is not ready", dependencyName(key))
+		}
+
+		if dep.MinRevision != "" && !revisionAtLeast(dependency.Status.LastAppliedRevision, dep.MinRevision) {
+			return fmt.Errorf("dependency %s applied revision '%s' which is lower than %s",
+				dependencyName(key), dependency.Status.LastAppliedRevision, dep.MinRevision)
+		}
+	}
+
+	return nil
+}
+
+// getDependency returns the Bootstrap, or for a ClusterBootstrap the ClusterBootstrap, with the given key.
+func (r *BootstrapReconciler) getDependency(ctx context.Context, obj *v1alpha1.Bootstrap, key types.NamespacedName) (*v1alpha1.Bootstrap, error) {
+	if isClusterBootstrap(obj) {
+		cluster := &v1alpha1.ClusterBootstrap{}
+		if err := r.Get(ctx, key, cluster); err != nil {
+			return nil, dependencyError(key, err)
+		}
+
+		return cluster.AsBootstrap(""), nil
+	}
+
+	dependency := &v1alpha1.Bootstrap{}
+	if err := r.Get(ctx, key, dependency); err != nil {
+		return nil, dependencyError(key, err)
+	}
+
+	return dependency, nil
+}
+
+func dependencyError(key types.NamespacedName, err error) error {
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("dependency %s not found", dependencyName(key))
+	}
+
+	return fmt.Errorf("failed to get dependency %s: %w", dependencyName(key), err)
+}
+
+// dependencyName returns the key of the dependency without the separator if it has no namespace.
+func dependencyName(key types.NamespacedName) string {
+	if key.Namespace == "" {
+		return key.Name
+	}
+
+	return key.String()
+}
+
+// revisionAtLeast returns whether revision is at least minimum. Revisions that aren't semantic versions, like
+// digests, have no order, so they must be equal.
+func revisionAtLeast(revision, minimum string) bool {
+	current, err := semver.NewVersion(revision)
+	if err != nil {
+		return revision == minimum
+	}
+
+	lowest, err := semver.NewVersion(minimum)
+	if err != nil {
+		return revision == minimum
+	}
+
+	return !current.LessThan(lowest)
+}
+
+// dependencyRequeueInterval returns the interval after which a Bootstrap waiting on its dependencies is retried.
+func (r *BootstrapReconciler) dependencyRequeueInterval() time.Duration {
+	if r.DependencyRequeueInterval > 0 {
+		return r.DependencyRequeueInterval
+	}
+
+	return defaultDependencyRequeueInterval
+}
+
+// dependencyReadyPredicate passes updates of Bootstraps that became ready or applied a new revision,
+// which are the changes dependent Bootstraps wait for.
+func dependencyReadyPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(event.CreateEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldObj, ok := asBootstrap(e.ObjectOld)
+			if !ok {
+				return false
+			}
+
+			newObj, ok := asBootstrap(e.ObjectNew)
+			if !ok {
+				return false
+			}
+
+			return (!conditions.IsReady(oldObj) && conditions.IsReady(newObj)) ||
+				oldObj.Status.LastAppliedRevision != newObj.Status.LastAppliedRevision
+		},
+		DeleteFunc: func(event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// asBootstrap returns the Bootstrap, or the Bootstrap created from a ClusterBootstrap.
+func asBootstrap(o client.Object) (*v1alpha1.Bootstrap, bool) {
+	switch obj := o.(type) {
+	case *v1alpha1.Bootstrap:
+		return obj, true
+	case *v1alpha1.ClusterBootstrap:
+		return obj.AsBootstrap(""), true
+	default:
+		return nil, false
+	}
+}
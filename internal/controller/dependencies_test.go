@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+func newDependency(name, namespace, revision string, ready bool) *v1alpha1.Bootstrap {
+	obj := &v1alpha1.Bootstrap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Generation: 1},
+		Status:     v1alpha1.BootstrapStatus{ObservedGeneration: 1, LastAppliedRevision: revision},
+	}
+
+	if ready {
+		conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "Successfully applied crd(s)")
+	} else {
+		conditions.MarkFalse(obj, meta.ReadyCondition, "CRDFetchFailed", "failed to fetch source")
+	}
+
+	return obj
+}
+
+func TestCheckDependencies(t *testing.T) {
+	stale := newDependency("stale", "default", "v1.0.0", true)
+	stale.Generation = 2
+
+	tests := []struct {
+		name        string
+		dependsOn   []v1alpha1.Dependency
+		expectedErr string
+	}{
+		{
+			name: "all dependencies ready",
+			dependsOn: []v1alpha1.Dependency{
+				{Name: "gateway-api", Namespace: "gateway-system", MinRevision: "v1.1.0"},
+				{Name: "digest", MinRevision: "sha256:abc"},
+			},
+		},
+		{
+			name:        "dependency missing",
+			dependsOn:   []v1alpha1.Dependency{{Name: "missing"}},
+			expectedErr: "dependency default/missing not found",
+		},
+		{
+			name:        "dependency not ready",
+			dependsOn:   []v1alpha1.Dependency{{Name: "failing"}},
+			expectedErr: "dependency default/failing is not ready",
+		},
+		{
+			name:        "dependency not reconciled yet",
+			dependsOn:   []v1alpha1.Dependency{{Name: "stale"}},
+			expectedErr: "dependency default/stale is not ready",
+		},
+		{
+			name:        "dependency revision too low",
+			dependsOn:   []v1alpha1.Dependency{{Name: "gateway-api", Namespace: "gateway-system", MinRevision: "v1.2.0"}},
+			expectedErr: "dependency gateway-system/gateway-api applied revision 'v1.1.0' which is lower than v1.2.0",
+		},
+		{
+			name:        "dependency digest differs",
+			dependsOn:   []v1alpha1.Dependency{{Name: "digest", MinRevision: "sha256:def"}},
+			expectedErr: "dependency default/digest applied revision 'sha256:abc' which is lower than sha256:def",
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	r := &BootstrapReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			newDependency("gateway-api", "gateway-system", "v1.1.0", true),
+			newDependency("digest", "default", "sha256:abc", true),
+			newDependency("failing", "default", "", false),
+			stale,
+		).Build(),
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &v1alpha1.Bootstrap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec:       v1alpha1.BootstrapSpec{DependsOn: tt.dependsOn},
+			}
+
+			err := r.checkDependencies(t.Context(), obj)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestReconcileWaitsForDependencies(t *testing.T) {
+	obj := &v1alpha1.Bootstrap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: v1alpha1.BootstrapSpec{
+			Interval:  metav1.Duration{Duration: time.Minute},
+			DependsOn: []v1alpha1.Dependency{{Name: "gateway-api"}},
+		},
+	}
+
+	r, src := newTestReconciler(t, obj)
+	r.DependencyRequeueInterval = 5 * time.Second
+
+	result, err := r.Reconcile(t.Context(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+	require.NoError(t, err)
+	assert.Equal(t, ctrl.Result{RequeueAfter: 5 * time.Second}, result)
+	assert.Equal(t, 0, src.checked)
+
+	updated := &v1alpha1.Bootstrap{}
+	require.NoError(t, r.Get(t.Context(), client.ObjectKeyFromObject(obj), updated))
+
+	condition := conditions.Get(updated, meta.ReadyCondition)
+	require.NotNil(t, condition)
+	assert.Equal(t, meta.DependencyNotReadyReason, condition.Reason)
+	assert.Equal(t, "dependency default/gateway-api not found", condition.Message)
+}
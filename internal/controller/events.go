@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	eventv1 "github.com/fluxcd/pkg/apis/event/v1beta1"
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+	"github.com/Skarlso/crd-bootstrap/internal/notification"
+)
+
+// breakingChangeDetectedReason is the reason of the Ready condition if an upgrade was blocked by breaking changes.
+const breakingChangeDetectedReason = "BreakingChangeDetected"
+
+// event records an event for the Bootstrap. If revision is set, it's added to the event metadata so
+// notification providers can display it.
+func (r *BootstrapReconciler) event(obj *v1alpha1.Bootstrap, revision, eventType, reason, messageFmt string, args ...any) {
+	if r.EventRecorder == nil {
+		return
+	}
+
+	var annotations map[string]string
+	if revision != "" {
+		annotations = map[string]string{
+			eventv1.Group + "/" + eventv1.MetaRevisionKey: revision,
+		}
+	}
+
+	r.EventRecorder.AnnotatedEventf(eventObject(obj), annotations, eventType, reason, messageFmt, args...)
+}
+
+// markFailed sets the Ready condition to false and records a warning event with the same reason and message.
+// A notification is only sent if the condition changed, so retrying the same failure doesn't send it again.
+func (r *BootstrapReconciler) markFailed(ctx context.Context, obj *v1alpha1.Bootstrap, revision, reason, messageFmt string, args ...any) {
+	message := fmt.Sprintf(messageFmt, args...)
+	previous := conditions.Get(obj, meta.ReadyCondition)
+	changed := previous == nil || previous.Reason != reason || previous.Message != message
+
+	conditions.MarkFalse(obj, meta.ReadyCondition, reason, "%s", message)
+
+	r.event(obj, revision, corev1.EventTypeWarning, reason, "%s", message)
+
+	if !changed {
+		return
+	}
+
+	event := notification.Event{
+		Reason:      notification.ReasonFailed,
+		OldRevision: obj.Status.LastAppliedRevision,
+		NewRevision: revision,
+		Error:       message,
+	}
+
+	if reason == breakingChangeDetectedReason {
+		event.Reason = notification.ReasonBlocked
+		event.BreakingChanges = obj.Status.BreakingChanges
+	}
+
+	r.notify(ctx, obj, event)
+}
+
+// notify sends the notification for the Bootstrap in the background, so the reconcile doesn't wait on the webhook.
+// Failing to notify doesn't fail the reconcile, it's recorded as an event instead.
+func (r *BootstrapReconciler) notify(ctx context.Context, obj *v1alpha1.Bootstrap, event notification.Event) {
+	if r.Notifier == nil {
+		return
+	}
+
+	event.Bootstrap = notification.Object{Kind: objectKind(obj), Name: obj.Name, Namespace: objectNamespace(obj)}
+	event.Timestamp = time.Now().UTC()
+
+	// the failure is reported after the reconcile may have modified the object.
+	failed := obj.DeepCopy()
+
+	r.Notifier.NotifyAsync(ctx, obj, event, func(err error) {
+		log.FromContext(ctx).Error(err, "failed to send notification")
+		r.event(failed, event.NewRevision, corev1.EventTypeWarning, "NotificationFailed", "failed to send notification: %s", err)
+	})
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/fluxcd/pkg/runtime/patch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+	"github.com/Skarlso/crd-bootstrap/internal/notification"
+)
+
+func TestMarkFailed(t *testing.T) {
+	recorder := record.NewFakeRecorder(1)
+	r := &BootstrapReconciler{EventRecorder: recorder}
+	obj := &v1alpha1.Bootstrap{}
+
+	r.markFailed(t.Context(), obj, "v1.0.0", "CRDFetchFailed", "failed to fetch source: %s", "boom")
+
+	condition := conditions.Get(obj, meta.ReadyCondition)
+	require.NotNil(t, condition)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, "CRDFetchFailed", condition.Reason)
+	assert.Equal(t, "failed to fetch source: boom", condition.Message)
+
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t,
+		"Warning CRDFetchFailed failed to fetch source: boom map[event.toolkit.fluxcd.io/revision:v1.0.0]",
+		<-recorder.Events)
+}
+
+func TestReconcileDeleteRecordsPrunedCRDs(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	obj := &v1alpha1.Bootstrap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", Finalizers: []string{finalizer}},
+		Spec:       v1alpha1.BootstrapSpec{Prune: true},
+		Status:     v1alpha1.BootstrapStatus{LastAppliedRevision: "v1.0.0"},
+	}
+	crd := &v1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "tests.example.com",
+			Labels: map[string]string{v1alpha1.BootstrapOwnerLabelKey: "test"},
+		},
+	}
+
+	recorder := record.NewFakeRecorder(1)
+	r := &BootstrapReconciler{
+		Client:        fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj, crd).Build(),
+		EventRecorder: recorder,
+	}
+
+	patchHelper := patch.NewSerialPatcher(obj, r.Client)
+	require.NoError(t, r.reconcileDelete(t.Context(), obj, func(ctx context.Context) error {
+		return patchHelper.Patch(ctx, obj)
+	}))
+
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t,
+		"Normal CRDsPruned pruned CRDs: tests.example.com map[event.toolkit.fluxcd.io/revision:v1.0.0]",
+		<-recorder.Events)
+}
+
+func TestMarkFailedNotifiesOnlyOnChange(t *testing.T) {
+	var notifications atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		notifications.Add(1)
+	}))
+	t.Cleanup(server.Close)
+
+	r := &BootstrapReconciler{
+		Notifier: notification.NewNotifier(fake.NewClientBuilder().Build(), http.DefaultClient, 0, &notification.Target{Address: server.URL}),
+	}
+	obj := &v1alpha1.Bootstrap{}
+
+	r.markFailed(t.Context(), obj, "v1.0.0", "CRDFetchFailed", "failed to fetch source: %s", "boom")
+	r.markFailed(t.Context(), obj, "v1.0.0", "CRDFetchFailed", "failed to fetch source: %s", "boom")
+	assert.Eventually(t, func() bool { return notifications.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	r.markFailed(t.Context(), obj, "v1.0.0", "CRDFetchFailed", "failed to fetch source: %s", "other")
+	assert.Eventually(t, func() bool { return notifications.Load() == 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), notifications.Load())
+}
+
+func TestMarkFailedDoesNotWaitOnHangingWebhook(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	r := &BootstrapReconciler{
+		Notifier: notification.NewNotifier(fake.NewClientBuilder().Build(), http.DefaultClient, 0, &notification.Target{Address: server.URL}),
+	}
+
+	start := time.Now()
+	r.markFailed(t.Context(), &v1alpha1.Bootstrap{}, "v1.0.0", "CRDFetchFailed", "failed to fetch source: %s", "boom")
+	assert.Less(t, time.Since(start), time.Second)
+}
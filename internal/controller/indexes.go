@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+const (
+	// configMapRefIndexKey indexes Bootstraps by the namespace/name of every ConfigMap they reference.
+	configMapRefIndexKey = ".spec.configMapRefs"
+	// secretRefIndexKey indexes Bootstraps by the namespace/name of every Secret they reference.
+	secretRefIndexKey = ".spec.secretRefs"
+)
+
+// indexConfigMapRefs returns the source, kubeconfig, target and template ConfigMaps referenced by a Bootstrap.
+func indexConfigMapRefs(o client.Object) []string {
+	obj, ok := o.(*v1alpha1.Bootstrap)
+	if !ok {
+		return nil
+	}
+
+	var refs []string
+
+	if obj.Spec.Source != nil && obj.Spec.Source.ConfigMap != nil {
+		refs = append(refs, types.NamespacedName{
+			Namespace: obj.Spec.Source.ConfigMap.Namespace,
+			Name:      obj.Spec.Source.ConfigMap.Name,
+		}.String())
+	}
+
+	for _, kc := range kubeConfigs(obj) {
+		if kc.SecretRef != nil && kc.SecretRef.ConfigMapRef != nil {
+			refs = append(refs, types.NamespacedName{Namespace: kubeConfigNamespace(obj, kc), Name: kc.SecretRef.ConfigMapRef.Name}.String())
+		}
+	}
+
+	for _, t := range obj.Spec.Templates {
+		if t.ConfigMapRef != nil {
+			refs = append(refs, types.NamespacedName{Namespace: obj.Namespace, Name: t.ConfigMapRef.Name}.String())
+		}
+	}
+
+	return refs
+}
+
+// indexSecretRefs returns the credential, certificate, proxy, notification, kubeconfig and target Secrets referenced by a Bootstrap.
+func indexSecretRefs(o client.Object) []string {
+	obj, ok := o.(*v1alpha1.Bootstrap)
+	if !ok {
+		return nil
+	}
+
+	var refs []string
+
+	if src := obj.Spec.Source; src != nil {
+		var ref *corev1.LocalObjectReference
+
+		switch {
+		case src.GitHub != nil:
+			ref = src.GitHub.SecretRef
+		case src.GitLab != nil:
+			ref = src.GitLab.SecretRef
+		case src.Helm != nil:
+			ref = src.Helm.SecretRef
+		case src.URL != nil:
+			ref = src.URL.SecretRef
+		}
+
+		if ref != nil {
+			refs = append(refs, types.NamespacedName{Namespace: obj.Namespace, Name: ref.Name}.String())
+		}
+
+		if t := src.GetTransport(); t != nil {
+			for _, ref := range []*corev1.LocalObjectReference{t.CertSecretRef, t.ProxySecretRef} {
+				if ref != nil {
+					refs = append(refs, types.NamespacedName{Namespace: obj.Namespace, Name: ref.Name}.String())
+				}
+			}
+		}
+	}
+
+	if obj.Spec.Notification != nil {
+		refs = append(refs, types.NamespacedName{Namespace: obj.Namespace, Name: obj.Spec.Notification.SecretRef.Name}.String())
+	}
+
+	for _, kc := range kubeConfigs(obj) {
+		if kc.SecretRef != nil && kc.SecretRef.SecretRef != nil {
+			refs = append(refs, types.NamespacedName{Namespace: kubeConfigNamespace(obj, kc), Name: kc.SecretRef.SecretRef.Name}.String())
+		}
+	}
+
+	return refs
+}
+
+// kubeConfigs returns the kubeconfig and the kubeconfigs of the target clusters defined by the Bootstrap.
+func kubeConfigs(obj *v1alpha1.Bootstrap) []*v1alpha1.KubeConfig {
+	var kcs []*v1alpha1.KubeConfig
+
+	if obj.Spec.KubeConfig != nil {
+		kcs = append(kcs, obj.Spec.KubeConfig)
+	}
+
+	if obj.Spec.Targets != nil {
+		for i := range obj.Spec.Targets.Clusters {
+			kcs = append(kcs, &obj.Spec.Targets.Clusters[i].KubeConfig)
+		}
+	}
+
+	return kcs
+}
+
+// kubeConfigNamespace returns the namespace the references of the kubeconfig are looked up in.
+func kubeConfigNamespace(obj *v1alpha1.Bootstrap, kc *v1alpha1.KubeConfig) string {
+	if kc.Namespace != "" {
+		return kc.Namespace
+	}
+
+	return obj.Namespace
+}
+
+// requestsForReferencedObject returns a map function that enqueues every Bootstrap that references
+// the changed object through the given index.
+func (r *BootstrapReconciler) requestsForReferencedObject(indexKey string) func(ctx context.Context, o client.Object) []reconcile.Request {
+	return func(ctx context.Context, o client.Object) []reconcile.Request {
+		logger := log.FromContext(ctx)
+
+		list := &v1alpha1.BootstrapList{}
+		if err := r.List(ctx, list, client.MatchingFields{indexKey: client.ObjectKeyFromObject(o).String()}); err != nil {
+			logger.Error(err, "failed to list bootstraps referencing object", "name", o.GetName(), "namespace", o.GetNamespace())
+
+			return nil
+		}
+
+		requests := make([]reconcile.Request, 0, len(list.Items))
+		for _, item := range list.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+		}
+
+		return requests
+	}
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+func TestRequestsForReferencedObject(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	configMapSource := &v1alpha1.Bootstrap{
+		ObjectMeta: metav1.ObjectMeta{Name: "configmap", Namespace: "default"},
+		Spec: v1alpha1.BootstrapSpec{
+			Source: &v1alpha1.Source{ConfigMap: &v1alpha1.ConfigMap{Name: "crds", Namespace: "crd-system"}},
+		},
+	}
+	githubSource := &v1alpha1.Bootstrap{
+		ObjectMeta: metav1.ObjectMeta{Name: "github", Namespace: "default"},
+		Spec: v1alpha1.BootstrapSpec{
+			Source: &v1alpha1.Source{GitHub: &v1alpha1.GitHub{SecretRef: &corev1.LocalObjectReference{Name: "token"}}},
+			KubeConfig: &v1alpha1.KubeConfig{
+				SecretRef: &meta.KubeConfigReference{SecretRef: &meta.SecretKeyReference{Name: "kubeconfig"}},
+			},
+			Templates: []v1alpha1.Template{
+				{ConfigMapRef: &corev1.LocalObjectReference{Name: "crds"}},
+			},
+		},
+	}
+	targets := &v1alpha1.Bootstrap{
+		ObjectMeta: metav1.ObjectMeta{Name: "targets", Namespace: "default"},
+		Spec: v1alpha1.BootstrapSpec{
+			Source: &v1alpha1.Source{GitHub: &v1alpha1.GitHub{
+				Transport: v1alpha1.Transport{CertSecretRef: &corev1.LocalObjectReference{Name: "ca"}},
+			}},
+			Targets: &v1alpha1.Targets{
+				Clusters: []v1alpha1.Target{
+					{
+						Name: "east",
+						KubeConfig: v1alpha1.KubeConfig{
+							SecretRef: &meta.KubeConfigReference{SecretRef: &meta.SecretKeyReference{Name: "east"}},
+							Namespace: "fleet",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	r := &BootstrapReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(configMapSource, githubSource, targets).
+			WithIndex(&v1alpha1.Bootstrap{}, configMapRefIndexKey, indexConfigMapRefs).
+			WithIndex(&v1alpha1.Bootstrap{}, secretRefIndexKey, indexSecretRefs).
+			Build(),
+	}
+
+	tests := []struct {
+		name     string
+		indexKey string
+		object   types.NamespacedName
+		expected []string
+	}{
+		{
+			name:     "source configmap in another namespace",
+			indexKey: configMapRefIndexKey,
+			object:   types.NamespacedName{Namespace: "crd-system", Name: "crds"},
+			expected: []string{"configmap"},
+		},
+		{
+			name:     "template configmap in the bootstrap namespace",
+			indexKey: configMapRefIndexKey,
+			object:   types.NamespacedName{Namespace: "default", Name: "crds"},
+			expected: []string{"github"},
+		},
+		{
+			name:     "source secret",
+			indexKey: secretRefIndexKey,
+			object:   types.NamespacedName{Namespace: "default", Name: "token"},
+			expected: []string{"github"},
+		},
+		{
+			name:     "source cert secret",
+			indexKey: secretRefIndexKey,
+			object:   types.NamespacedName{Namespace: "default", Name: "ca"},
+			expected: []string{"targets"},
+		},
+		{
+			name:     "kubeconfig secret",
+			indexKey: secretRefIndexKey,
+			object:   types.NamespacedName{Namespace: "default", Name: "kubeconfig"},
+			expected: []string{"github"},
+		},
+		{
+			name:     "target kubeconfig secret",
+			indexKey: secretRefIndexKey,
+			object:   types.NamespacedName{Namespace: "fleet", Name: "east"},
+			expected: []string{"targets"},
+		},
+		{
+			name:     "unreferenced object",
+			indexKey: secretRefIndexKey,
+			object:   types.NamespacedName{Namespace: "default", Name: "other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: tt.object.Name, Namespace: tt.object.Namespace}}
+
+			requests := r.requestsForReferencedObject(tt.indexKey)(t.Context(), obj)
+
+			expected := make([]reconcile.Request, 0, len(tt.expected))
+			for _, name := range tt.expected {
+				expected = append(expected, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}})
+			}
+
+			assert.ElementsMatch(t, expected, requests)
+		})
+	}
+}
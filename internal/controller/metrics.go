@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+	"github.com/Skarlso/crd-bootstrap/pkg/source"
+)
+
+const (
+	// operationCheck is the metrics operation label of checking a source for updates.
+	operationCheck = "check"
+	// operationFetch is the metrics operation label of fetching CRDs from a source.
+	operationFetch = "fetch"
+)
+
+// recordReadiness records the Ready condition and the duration of the reconcile since start.
+func (r *BootstrapReconciler) recordReadiness(obj *v1alpha1.Bootstrap, start time.Time) {
+	if r.MetricsRecorder == nil {
+		return
+	}
+
+	ref := objectReference(obj)
+
+	if condition := conditions.Get(obj, meta.ReadyCondition); condition != nil {
+		r.MetricsRecorder.RecordCondition(ref, *condition)
+	}
+
+	r.MetricsRecorder.RecordDuration(ref, start)
+}
+
+// recordSuspension records whether the Bootstrap is suspended.
+func (r *BootstrapReconciler) recordSuspension(obj *v1alpha1.Bootstrap) {
+	if r.MetricsRecorder == nil {
+		return
+	}
+
+	r.MetricsRecorder.RecordSuspend(objectReference(obj), obj.Spec.Suspend)
+}
+
+// deleteMetrics removes all metrics of a deleted Bootstrap.
+func (r *BootstrapReconciler) deleteMetrics(obj *v1alpha1.Bootstrap) {
+	if r.MetricsRecorder == nil {
+		return
+	}
+
+	ref := objectReference(obj)
+
+	r.MetricsRecorder.DeleteCondition(ref, meta.ReadyCondition)
+	r.MetricsRecorder.DeleteDuration(ref)
+	r.MetricsRecorder.DeleteSuspend(ref)
+	r.MetricsRecorder.Delete(obj.Name, objectNamespace(obj))
+}
+
+// recordSourceRequest records the duration and the result of a source operation.
+func (r *BootstrapReconciler) recordSourceRequest(obj *v1alpha1.Bootstrap, operation string, start time.Time, err error) {
+	if r.MetricsRecorder == nil {
+		return
+	}
+
+	t, _ := source.TypeOf(obj.Spec.Source)
+
+	r.MetricsRecorder.RecordSourceRequest(string(t), sourceHost(obj.Spec.Source), operation, start, err)
+}
+
+func objectReference(obj *v1alpha1.Bootstrap) corev1.ObjectReference {
+	return corev1.ObjectReference{
+		Kind:      objectKind(obj),
+		Name:      obj.Name,
+		Namespace: objectNamespace(obj),
+	}
+}
+
+// sourceHost returns the host a source is fetched from. ConfigMap sources don't have a host.
+func sourceHost(src *v1alpha1.Source) string {
+	if src == nil {
+		return ""
+	}
+
+	var address string
+
+	switch {
+	case src.GitHub != nil:
+		address = src.GitHub.BaseAPIURL
+		if address == "" {
+			address = "https://api.github.com"
+		}
+	case src.GitLab != nil:
+		address = src.GitLab.BaseAPIURL
+		if address == "" {
+			address = "https://gitlab.com"
+		}
+	case src.Helm != nil:
+		address = src.Helm.ChartReference
+	case src.URL != nil:
+		address = src.URL.URL
+	default:
+		return ""
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return ""
+	}
+
+	return u.Host
+}
+
+// sourceAttributes returns the span attributes describing the source of a Bootstrap.
+func sourceAttributes(obj *v1alpha1.Bootstrap) trace.SpanStartEventOption {
+	t, _ := source.TypeOf(obj.Spec.Source)
+
+	return trace.WithAttributes(
+		attribute.String("source.type", string(t)),
+		attribute.String("source.host", sourceHost(obj.Spec.Source)),
+	)
+}
@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+const (
+	// policyCompliantCondition reports whether the CRDs of the last attempted revision are allowed by the
+	// CRD policies that apply to the Bootstrap.
+	policyCompliantCondition = "PolicyCompliant"
+	// policyViolationReason is the reason of the conditions if a CRD isn't allowed by a policy.
+	policyViolationReason = "PolicyViolation"
+)
+
+//+kubebuilder:rbac:groups=delivery.crd-bootstrap,resources=crdpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// checkPolicies returns a violation for every CRD that isn't allowed by a policy that applies to the Bootstrap.
+func (r *BootstrapReconciler) checkPolicies(ctx context.Context, obj *v1alpha1.Bootstrap, objects []*unstructured.Unstructured) ([]string, error) {
+	policies := &v1alpha1.CRDPolicyList{}
+	if err := r.List(ctx, policies); err != nil {
+		return nil, fmt.Errorf("failed to list CRD policies: %w", err)
+	}
+
+	namespaceLabels, err := r.namespaceLabels(ctx, obj, policies.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+
+	for _, policy := range policies.Items {
+		applies, err := policyApplies(&policy, obj, namespaceLabels)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CRD policy %s: %w", policy.Name, err)
+		}
+
+		if !applies {
+			continue
+		}
+
+		for _, o := range objects {
+			allowed, err := policyAllows(&policy, o)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CRD policy %s: %w", policy.Name, err)
+			}
+
+			if !allowed {
+				violations = append(violations, fmt.Sprintf("CRD %s isn't allowed by policy %s", o.GetName(), policy.Name))
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// namespaceLabels returns the labels of the namespace of the Bootstrap if a policy selects namespaces by them.
+func (r *BootstrapReconciler) namespaceLabels(ctx context.Context, obj *v1alpha1.Bootstrap, policies []v1alpha1.CRDPolicy) (labels.Set, error) {
+	if isClusterBootstrap(obj) || !slices.ContainsFunc(policies, func(p v1alpha1.CRDPolicy) bool { return p.Spec.NamespaceSelector != nil }) {
+		return nil, nil
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: obj.Namespace}, namespace); err != nil {
+		return nil, fmt.Errorf("failed to find namespace %s: %w", obj.Namespace, err)
+	}
+
+	return namespace.Labels, nil
+}
+
+// policyApplies returns whether the policy selects the Bootstrap. namespaceLabels are the labels of the namespace of
+// the Bootstrap.
+func policyApplies(policy *v1alpha1.CRDPolicy, obj *v1alpha1.Bootstrap, namespaceLabels labels.Set) (bool, error) {
+	if len(policy.Spec.Namespaces) > 0 {
+		if isClusterBootstrap(obj) {
+			return false, nil
+		}
+
+		matched, err := matchAny(policy.Spec.Namespaces, obj.Namespace)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	if policy.Spec.NamespaceSelector != nil {
+		if isClusterBootstrap(obj) {
+			return false, nil
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(policy.Spec.NamespaceSelector)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse namespace selector: %w", err)
+		}
+
+		if !selector.Matches(namespaceLabels) {
+			return false, nil
+		}
+	}
+
+	if policy.Spec.Selector == nil {
+		return true, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(policy.Spec.Selector)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse selector: %w", err)
+	}
+
+	return selector.Matches(labels.Set(obj.Labels)), nil
+}
+
+// policyAllows returns whether the CRD isn't denied and, if the policy has allow rules, is allowed by one of them.
+func policyAllows(policy *v1alpha1.CRDPolicy, crd *unstructured.Unstructured) (bool, error) {
+	for _, rule := range policy.Spec.Deny {
+		matched, err := ruleMatches(rule, crd)
+		if err != nil || matched {
+			return false, err
+		}
+	}
+
+	if len(policy.Spec.Allow) == 0 {
+		return true, nil
+	}
+
+	for _, rule := range policy.Spec.Allow {
+		matched, err := ruleMatches(rule, crd)
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+
+	return false, nil
+}
+
+// ruleMatches returns whether every field defined by the rule matches the CRD.
+func ruleMatches(rule v1alpha1.CRDRule, crd *unstructured.Unstructured) (bool, error) {
+	group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+	scope, _, _ := unstructured.NestedString(crd.Object, "spec", "scope")
+
+	if len(rule.Scopes) > 0 && !slices.Contains(rule.Scopes, apiextensionsv1.ResourceScope(scope)) {
+		return false, nil
+	}
+
+	if len(rule.Groups) > 0 {
+		matched, err := matchAny(rule.Groups, group)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	if len(rule.Names) > 0 {
+		return matchAny(rule.Names, crd.GetName())
+	}
+
+	return true, nil
+}
+
+// matchAny returns whether the value matches one of the glob patterns.
+func matchAny(patterns []string, value string) (bool, error) {
+	for _, pattern := range patterns {
+		matched, err := path.Match(pattern, value)
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+
+		if matched {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// requestsForAllBootstraps enqueues every Bootstrap, so Bootstraps blocked by a policy are retried when policies change.
+func (r *BootstrapReconciler) requestsForAllBootstraps(ctx context.Context, _ client.Object) []reconcile.Request {
+	list := &v1alpha1.BootstrapList{}
+	if err := r.List(ctx, list); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list bootstraps")
+
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, item := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+	}
+
+	return requests
+}
@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+func newCRD(name, group, scope string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]any{"name": name},
+		"spec":       map[string]any{"group": group, "scope": scope},
+	}}
+}
+
+func TestCheckPolicies(t *testing.T) {
+	gateway := newCRD("gateways.gateway.networking.k8s.io", "gateway.networking.k8s.io", "Namespaced")
+	widget := newCRD("widgets.example.com", "example.com", "Namespaced")
+	clusterWidget := newCRD("clusterwidgets.example.com", "example.com", "Cluster")
+
+	tests := []struct {
+		name               string
+		policies           []v1alpha1.CRDPolicySpec
+		cluster            bool
+		expectedViolations []string
+		expectedErr        string
+	}{
+		{
+			name: "no policies",
+		},
+		{
+			name: "denied group",
+			policies: []v1alpha1.CRDPolicySpec{
+				{Deny: []v1alpha1.CRDRule{{Groups: []string{"*.k8s.io"}}}},
+			},
+			expectedViolations: []string{"CRD gateways.gateway.networking.k8s.io isn't allowed by policy policy-0"},
+		},
+		{
+			name: "only allowed groups and scopes",
+			policies: []v1alpha1.CRDPolicySpec{
+				{Allow: []v1alpha1.CRDRule{{Groups: []string{"example.com"}, Scopes: []apiextensionsv1.ResourceScope{apiextensionsv1.NamespaceScoped}}}},
+			},
+			expectedViolations: []string{
+				"CRD gateways.gateway.networking.k8s.io isn't allowed by policy policy-0",
+				"CRD clusterwidgets.example.com isn't allowed by policy policy-0",
+			},
+		},
+		{
+			name: "deny overrides allow",
+			policies: []v1alpha1.CRDPolicySpec{
+				{
+					Allow: []v1alpha1.CRDRule{{Groups: []string{"*"}}},
+					Deny:  []v1alpha1.CRDRule{{Names: []string{"widgets.*"}}},
+				},
+			},
+			expectedViolations: []string{"CRD widgets.example.com isn't allowed by policy policy-0"},
+		},
+		{
+			name: "every policy must allow",
+			policies: []v1alpha1.CRDPolicySpec{
+				{Allow: []v1alpha1.CRDRule{{Groups: []string{"*.k8s.io", "example.com"}}}},
+				{Deny: []v1alpha1.CRDRule{{Scopes: []apiextensionsv1.ResourceScope{apiextensionsv1.ClusterScoped}}}},
+			},
+			expectedViolations: []string{"CRD clusterwidgets.example.com isn't allowed by policy policy-1"},
+		},
+		{
+			name: "policy for other namespaces",
+			policies: []v1alpha1.CRDPolicySpec{
+				{Namespaces: []string{"team-*"}, Deny: []v1alpha1.CRDRule{{}}},
+			},
+		},
+		{
+			name: "policy for matching namespace",
+			policies: []v1alpha1.CRDPolicySpec{
+				{Namespaces: []string{"tenant-*"}, Deny: []v1alpha1.CRDRule{{Names: []string{"widgets.example.com"}}}},
+			},
+			expectedViolations: []string{"CRD widgets.example.com isn't allowed by policy policy-0"},
+		},
+		{
+			name: "namespaced policy doesn't apply to cluster bootstraps",
+			policies: []v1alpha1.CRDPolicySpec{
+				{Namespaces: []string{"*"}, Deny: []v1alpha1.CRDRule{{}}},
+			},
+			cluster: true,
+		},
+		{
+			name: "policy with selector",
+			policies: []v1alpha1.CRDPolicySpec{
+				{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "platform"}},
+					Deny:     []v1alpha1.CRDRule{{Names: []string{"widgets.example.com"}}},
+				},
+				{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "other"}},
+					Deny:     []v1alpha1.CRDRule{{}},
+				},
+			},
+			expectedViolations: []string{"CRD widgets.example.com isn't allowed by policy policy-0"},
+		},
+		{
+			name: "policy with namespace selector",
+			policies: []v1alpha1.CRDPolicySpec{
+				{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenancy": "shared"}},
+					Deny:              []v1alpha1.CRDRule{{Names: []string{"widgets.example.com"}}},
+				},
+				{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenancy": "dedicated"}},
+					Deny:              []v1alpha1.CRDRule{{}},
+				},
+			},
+			expectedViolations: []string{"CRD widgets.example.com isn't allowed by policy policy-0"},
+		},
+		{
+			name: "namespace selector doesn't apply to cluster bootstraps",
+			policies: []v1alpha1.CRDPolicySpec{
+				{NamespaceSelector: &metav1.LabelSelector{}, Deny: []v1alpha1.CRDRule{{}}},
+			},
+			cluster: true,
+		},
+		{
+			name: "invalid pattern",
+			policies: []v1alpha1.CRDPolicySpec{
+				{Deny: []v1alpha1.CRDRule{{Groups: []string{"[example.com"}}}},
+			},
+			expectedErr: "invalid CRD policy policy-0: invalid pattern [example.com: syntax error in pattern",
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a", Labels: map[string]string{"tenancy": "shared"}}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := []client.Object{namespace}
+			for i, spec := range tt.policies {
+				objects = append(objects, &v1alpha1.CRDPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("policy-%d", i)},
+					Spec:       spec,
+				})
+			}
+
+			r := &BootstrapReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()}
+
+			obj := &v1alpha1.Bootstrap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "tenant-a", Labels: map[string]string{"team": "platform"}},
+			}
+			if tt.cluster {
+				obj = (&v1alpha1.ClusterBootstrap{ObjectMeta: obj.ObjectMeta}).AsBootstrap("crd-bootstrap-system")
+			}
+
+			violations, err := r.checkPolicies(t.Context(), obj, []*unstructured.Unstructured{gateway, widget, clusterWidget})
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedViolations, violations)
+		})
+	}
+}
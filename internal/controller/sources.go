@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+	"github.com/Skarlso/crd-bootstrap/pkg/source"
+)
+
+const (
+	// rateLimitedReason is the reason of the Ready condition if the source rejected a request because of a rate limit.
+	rateLimitedReason = "RateLimited"
+	// notFoundReason is the reason of the Ready condition if the source doesn't have the repository, release, chart
+	// or file, which usually means the Bootstrap is misconfigured.
+	notFoundReason = "NotFound"
+	// unreachableReason is the reason of the Ready condition if the source couldn't be reached or failed to handle
+	// a request.
+	unreachableReason = "Unreachable"
+)
+
+// sourceFailure returns the reason of the Ready condition for an error returned by the source, falling back to
+// reason, and when to try again. Rate limited requests are retried once the limit is lifted, and missing sources at
+// the next interval, because retrying sooner won't help. Other errors are retried with the default backoff, which is
+// signalled by a zero duration.
+func sourceFailure(obj *v1alpha1.Bootstrap, err error, reason string) (string, time.Duration) {
+	switch {
+	case errors.Is(err, source.ErrRateLimited):
+		if wait, ok := source.RetryAfter(err); ok {
+			return rateLimitedReason, wait
+		}
+
+		return rateLimitedReason, obj.GetRequeueAfter()
+	case errors.Is(err, source.ErrNotFound):
+		return notFoundReason, obj.GetRequeueAfter()
+	case errors.Is(err, source.ErrUnreachable):
+		return unreachableReason, 0
+	default:
+		return reason, 0
+	}
+}
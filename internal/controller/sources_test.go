@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+	"github.com/Skarlso/crd-bootstrap/pkg/source"
+)
+
+func TestSourceFailure(t *testing.T) {
+	obj := &v1alpha1.Bootstrap{Spec: v1alpha1.BootstrapSpec{Interval: metav1.Duration{Duration: 10 * time.Minute}}}
+
+	rateLimited := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"30"}}}
+
+	tests := []struct {
+		name           string
+		err            error
+		expectedReason string
+		expectedRetry  time.Duration
+	}{
+		{
+			name:           "rate limited until reset",
+			err:            fmt.Errorf("failed: %w", source.NewStatusError(rateLimited)),
+			expectedReason: rateLimitedReason,
+			expectedRetry:  30 * time.Second,
+		},
+		{
+			name:           "rate limited without reset",
+			err:            source.NewStatusError(&http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}),
+			expectedReason: rateLimitedReason,
+			expectedRetry:  10 * time.Minute,
+		},
+		{
+			name:           "not found",
+			err:            source.NewStatusError(&http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}}),
+			expectedReason: notFoundReason,
+			expectedRetry:  10 * time.Minute,
+		},
+		{
+			name:           "unreachable",
+			err:            fmt.Errorf("%w: connection refused", source.ErrUnreachable),
+			expectedReason: unreachableReason,
+		},
+		{
+			name:           "other",
+			err:            errors.New("invalid semver"),
+			expectedReason: "VersionCheckFailed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, retry := sourceFailure(obj, tt.err, "VersionCheckFailed")
+
+			assert.Equal(t, tt.expectedReason, reason)
+			assert.Equal(t, tt.expectedRetry, retry)
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+// stage is a group of targets that is rolled out to before the following stages.
+type stage struct {
+	name    string
+	soak    time.Duration
+	targets []target
+}
+
+// groupStages assigns every target to the first stage that matches its name.
+func groupStages(stages []v1alpha1.Stage, targets []target) ([]stage, error) {
+	grouped := make([]stage, 0, len(stages))
+	for _, s := range stages {
+		grouped = append(grouped, stage{name: s.Name, soak: s.SoakDuration.Duration})
+	}
+
+	for _, t := range targets {
+		assigned := false
+
+		for i, s := range stages {
+			matched, err := matchAny(s.Targets, t.name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid stage %s: %w", s.Name, err)
+			}
+
+			if matched {
+				grouped[i].targets = append(grouped[i].targets, t)
+				assigned = true
+
+				break
+			}
+		}
+
+		if !assigned {
+			return nil, fmt.Errorf("target %s isn't part of any stage", t.name)
+		}
+	}
+
+	return grouped, nil
+}
+
+// stageStatus returns the last recorded status of the stage.
+func stageStatus(obj *v1alpha1.Bootstrap, name string) *v1alpha1.StageStatus {
+	for i := range obj.Status.Stages {
+		if obj.Status.Stages[i].Name == name {
+			return &obj.Status.Stages[i]
+		}
+	}
+
+	return nil
+}
+
+// reconcileStages rolls the revision out to one stage after the other. A stage is only rolled out to once the
+// previous stages are ready and soaked. The rollout halts at a stage that is soaking, in which case the remaining
+// soak duration is returned, or at the first stage with a failing target. A failed stage isn't retried with a
+// backoff, it's rolled out to again after the interval of the Bootstrap.
+func (r *BootstrapReconciler) reconcileStages(ctx context.Context, obj *v1alpha1.Bootstrap, targets []target, objects []*unstructured.Unstructured, revision string) (time.Duration, bool, error) {
+	stages, err := groupStages(obj.Spec.Targets.Stages, targets)
+	if err != nil {
+		r.markFailed(ctx, obj, revision, targetResolutionFailedReason, "failed to group targets into stages: %s", err)
+
+		return 0, false, fmt.Errorf("failed to group targets into stages: %w", err)
+	}
+
+	var (
+		rolled  []v1alpha1.TargetStatus
+		soak    time.Duration
+		soaking string
+		failed  string
+		halted  bool
+	)
+
+	statuses := make([]v1alpha1.StageStatus, 0, len(stages))
+
+	for i, s := range stages {
+		status := v1alpha1.StageStatus{
+			Name:         s.name,
+			Revision:     revision,
+			Phase:        v1alpha1.StagePending,
+			TotalTargets: len(s.targets),
+			Message:      "waiting for the previous stages",
+		}
+
+		var previousPhase v1alpha1.StagePhase
+		if previous := stageStatus(obj, s.name); previous != nil && previous.Revision == revision {
+			previousPhase = previous.Phase
+			status.SoakStartedAt = previous.SoakStartedAt
+		}
+
+		if halted {
+			status.SoakStartedAt = nil
+			statuses = append(statuses, status)
+
+			continue
+		}
+
+		// A stage that completed the revision isn't rolled out to again while the following stages progress.
+		var targetStatuses []v1alpha1.TargetStatus
+		if previousPhase == v1alpha1.StageCompleted {
+			targetStatuses = completedTargets(obj, s.targets, revision)
+		}
+
+		if targetStatuses == nil {
+			targetStatuses = r.rolloutTargets(ctx, obj, s.targets, objects, revision, true)
+		}
+
+		rolled = append(rolled, targetStatuses...)
+
+		for _, ts := range targetStatuses {
+			if ts.Ready {
+				status.ReadyTargets++
+			}
+		}
+
+		status, soak = progressStage(status, s.soak, i == len(stages)-1, time.Now())
+
+		switch status.Phase {
+		case v1alpha1.StageFailed:
+			failed = s.name
+			halted = true
+		case v1alpha1.StageSoaking:
+			soaking = s.name
+			halted = true
+		}
+
+		if status.Phase == v1alpha1.StageCompleted && previousPhase != v1alpha1.StageCompleted {
+			r.event(obj, revision, corev1.EventTypeNormal, "StageCompleted", "revision %s rolled out to stage %s", revision, s.name)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	obj.Status.Stages = statuses
+
+	if err := r.recordTargets(ctx, obj, targets, rolled, revision, failed); err != nil {
+		if failed == "" {
+			return 0, false, err
+		}
+
+		log.FromContext(ctx).Error(err, "rollout halted at a failed stage", "stage", failed)
+
+		return obj.GetRequeueAfter(), false, nil
+	}
+
+	if soak > 0 {
+		conditions.MarkUnknown(obj, meta.ReadyCondition, meta.ProgressingReason, "rolling out revision %s, stage %s is soaking", revision, soaking)
+
+		return soak, false, nil
+	}
+
+	return 0, true, nil
+}
+
+// completedTargets returns the recorded statuses of the targets of a stage that completed the revision. It returns
+// nil if one of them doesn't have the revision applied, such as a target that was added to the stage since.
+func completedTargets(obj *v1alpha1.Bootstrap, targets []target, revision string) []v1alpha1.TargetStatus {
+	statuses := make([]v1alpha1.TargetStatus, 0, len(targets))
+
+	for _, t := range targets {
+		status := targetStatus(obj, t.name)
+		if status == nil || !status.Ready || status.LastAppliedRevision != revision {
+			return nil
+		}
+
+		statuses = append(statuses, *status)
+	}
+
+	return statuses
+}
+
+// progressStage sets the phase of a stage after it was rolled out to and returns how long it still has to soak.
+func progressStage(status v1alpha1.StageStatus, soak time.Duration, last bool, now time.Time) (v1alpha1.StageStatus, time.Duration) {
+	if status.ReadyTargets < status.TotalTargets {
+		status.Phase = v1alpha1.StageFailed
+		status.SoakStartedAt = nil
+		status.Message = fmt.Sprintf("%d of %d targets failed, halting the rollout", status.TotalTargets-status.ReadyTargets, status.TotalTargets)
+
+		return status, 0
+	}
+
+	// Nothing waits for the last stage, so it doesn't soak.
+	if last {
+		status.Phase = v1alpha1.StageCompleted
+		status.Message = "revision applied to every target"
+
+		return status, 0
+	}
+
+	if status.SoakStartedAt == nil {
+		status.SoakStartedAt = &metav1.Time{Time: now}
+	}
+
+	end := status.SoakStartedAt.Add(soak)
+	if remaining := end.Sub(now); remaining > 0 {
+		status.Phase = v1alpha1.StageSoaking
+		status.Message = fmt.Sprintf("soaking until %s", end.UTC().Format(time.RFC3339))
+
+		return status, remaining
+	}
+
+	status.Phase = v1alpha1.StageCompleted
+	status.Message = "revision applied to every target and soaked"
+
+	return status, 0
+}
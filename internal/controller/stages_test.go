@@ -0,0 +1,233 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+func TestGroupStages(t *testing.T) {
+	targets := []target{{name: "canary"}, {name: "prod-eu"}, {name: "prod-us"}, {name: "dev"}}
+
+	tests := []struct {
+		name        string
+		stages      []v1alpha1.Stage
+		expected    map[string][]string
+		expectedErr string
+	}{
+		{
+			name: "first matching stage",
+			stages: []v1alpha1.Stage{
+				{Name: "canary", Targets: []string{"canary", "dev"}},
+				{Name: "production", Targets: []string{"prod-*", "*"}},
+			},
+			expected: map[string][]string{
+				"canary":     {"canary", "dev"},
+				"production": {"prod-eu", "prod-us"},
+			},
+		},
+		{
+			name: "target without stage",
+			stages: []v1alpha1.Stage{
+				{Name: "canary", Targets: []string{"canary", "dev"}},
+				{Name: "production", Targets: []string{"prod-eu"}},
+			},
+			expectedErr: "target prod-us isn't part of any stage",
+		},
+		{
+			name:        "invalid pattern",
+			stages:      []v1alpha1.Stage{{Name: "canary", Targets: []string{"[canary"}}},
+			expectedErr: "invalid stage canary: invalid pattern [canary: syntax error in pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stages, err := groupStages(tt.stages, targets)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			grouped := make(map[string][]string, len(stages))
+			for _, s := range stages {
+				grouped[s.name] = targetNames(s.targets)
+			}
+
+			assert.Equal(t, tt.expected, grouped)
+		})
+	}
+}
+
+func TestProgressStage(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	started := metav1.NewTime(now.Add(-time.Hour))
+
+	tests := []struct {
+		name              string
+		status            v1alpha1.StageStatus
+		soak              time.Duration
+		last              bool
+		expectedPhase     v1alpha1.StagePhase
+		expectedRemaining time.Duration
+		expectedStarted   *metav1.Time
+	}{
+		{
+			name:          "failed target",
+			status:        v1alpha1.StageStatus{ReadyTargets: 1, TotalTargets: 2, SoakStartedAt: &started},
+			soak:          time.Hour,
+			expectedPhase: v1alpha1.StageFailed,
+		},
+		{
+			name:              "starts soaking",
+			status:            v1alpha1.StageStatus{ReadyTargets: 2, TotalTargets: 2},
+			soak:              30 * time.Minute,
+			expectedPhase:     v1alpha1.StageSoaking,
+			expectedRemaining: 30 * time.Minute,
+			expectedStarted:   &metav1.Time{Time: now},
+		},
+		{
+			name:              "still soaking",
+			status:            v1alpha1.StageStatus{ReadyTargets: 2, TotalTargets: 2, SoakStartedAt: &started},
+			soak:              2 * time.Hour,
+			expectedPhase:     v1alpha1.StageSoaking,
+			expectedRemaining: time.Hour,
+			expectedStarted:   &started,
+		},
+		{
+			name:            "soaked",
+			status:          v1alpha1.StageStatus{ReadyTargets: 2, TotalTargets: 2, SoakStartedAt: &started},
+			soak:            time.Hour,
+			expectedPhase:   v1alpha1.StageCompleted,
+			expectedStarted: &started,
+		},
+		{
+			name:          "last stage doesn't soak",
+			status:        v1alpha1.StageStatus{ReadyTargets: 2, TotalTargets: 2},
+			soak:          time.Hour,
+			last:          true,
+			expectedPhase: v1alpha1.StageCompleted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, remaining := progressStage(tt.status, tt.soak, tt.last, now)
+
+			assert.Equal(t, tt.expectedPhase, status.Phase)
+			assert.Equal(t, tt.expectedRemaining, remaining)
+			assert.Equal(t, tt.expectedStarted, status.SoakStartedAt)
+		})
+	}
+}
+
+func TestReconcileStagesHaltsOnFailure(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	// The kubeconfig Secrets don't exist, so the canary stage fails.
+	r := &BootstrapReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).Build()}
+
+	obj := &v1alpha1.Bootstrap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: v1alpha1.BootstrapSpec{
+			Interval: metav1.Duration{Duration: 5 * time.Minute},
+			Targets: &v1alpha1.Targets{
+				Clusters: []v1alpha1.Target{{Name: "canary"}, {Name: "prod"}},
+				Stages: []v1alpha1.Stage{
+					{Name: "canary", Targets: []string{"canary"}, SoakDuration: metav1.Duration{Duration: time.Hour}},
+					{Name: "production", Targets: []string{"*"}},
+				},
+			},
+		},
+		Status: v1alpha1.BootstrapStatus{
+			Targets: []v1alpha1.TargetStatus{{Name: "prod", Ready: true, LastAppliedRevision: "v1.0.0"}},
+		},
+	}
+
+	targets, err := r.resolveTargets(t.Context(), obj)
+	require.NoError(t, err)
+
+	// The rollout halts instead of being retried with a backoff.
+	requeue, complete, err := r.reconcileStages(t.Context(), obj, targets, []*unstructured.Unstructured{}, "v1.1.0")
+	require.NoError(t, err)
+	assert.False(t, complete)
+	assert.Equal(t, 5*time.Minute, requeue)
+
+	require.Len(t, obj.Status.Stages, 2)
+	assert.Equal(t, v1alpha1.StageFailed, obj.Status.Stages[0].Phase)
+	assert.Equal(t, "v1.1.0", obj.Status.Stages[0].Revision)
+	assert.Equal(t, v1alpha1.StagePending, obj.Status.Stages[1].Phase)
+
+	// The production target wasn't rolled out to, so it keeps the last applied revision.
+	require.Len(t, obj.Status.Targets, 2)
+	assert.False(t, obj.Status.Targets[0].Ready)
+	assert.Equal(t, v1alpha1.TargetStatus{Name: "prod", Ready: true, LastAppliedRevision: "v1.0.0"}, obj.Status.Targets[1])
+
+	condition := conditions.Get(obj, meta.ReadyCondition)
+	require.NotNil(t, condition)
+	assert.Equal(t, "ResourceManagerCreateFailed", condition.Reason)
+	assert.Contains(t, condition.Message, "rollout halted at stage canary, failed to apply to 1 of 2 targets")
+}
+
+func TestReconcileStagesSkipsCompletedStages(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	// The kubeconfig Secrets don't exist, so every target that is rolled out to fails.
+	r := &BootstrapReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).Build()}
+
+	soaked := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	obj := &v1alpha1.Bootstrap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: v1alpha1.BootstrapSpec{
+			Interval: metav1.Duration{Duration: 5 * time.Minute},
+			Targets: &v1alpha1.Targets{
+				Clusters: []v1alpha1.Target{{Name: "canary"}, {Name: "prod"}},
+				Stages: []v1alpha1.Stage{
+					{Name: "canary", Targets: []string{"canary"}, SoakDuration: metav1.Duration{Duration: time.Hour}},
+					{Name: "production", Targets: []string{"*"}},
+				},
+			},
+		},
+		Status: v1alpha1.BootstrapStatus{
+			Stages: []v1alpha1.StageStatus{
+				{Name: "canary", Revision: "v1.1.0", Phase: v1alpha1.StageCompleted, ReadyTargets: 1, TotalTargets: 1, SoakStartedAt: &soaked},
+			},
+			Targets: []v1alpha1.TargetStatus{
+				{Name: "canary", Ready: true, LastAppliedRevision: "v1.1.0"},
+				{Name: "prod", Ready: true, LastAppliedRevision: "v1.0.0"},
+			},
+		},
+	}
+
+	targets, err := r.resolveTargets(t.Context(), obj)
+	require.NoError(t, err)
+
+	_, complete, err := r.reconcileStages(t.Context(), obj, targets, []*unstructured.Unstructured{}, "v1.1.0")
+	require.NoError(t, err)
+	assert.False(t, complete)
+
+	// The canary stage wasn't rolled out to again, only the production stage failed.
+	require.Len(t, obj.Status.Stages, 2)
+	assert.Equal(t, v1alpha1.StageCompleted, obj.Status.Stages[0].Phase)
+	assert.Equal(t, v1alpha1.StageFailed, obj.Status.Stages[1].Phase)
+
+	require.Len(t, obj.Status.Targets, 2)
+	assert.Equal(t, v1alpha1.TargetStatus{Name: "canary", Ready: true, LastAppliedRevision: "v1.1.0"}, obj.Status.Targets[0])
+	assert.False(t, obj.Status.Targets[1].Ready)
+}
@@ -0,0 +1,394 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/ssa"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+const (
+	// targetsFailedReason is the reason of the Ready condition if the CRDs couldn't be applied to every target.
+	targetsFailedReason = "TargetsFailed"
+	// targetResolutionFailedReason is the reason of the Ready condition if the targets couldn't be determined.
+	targetResolutionFailedReason = "TargetResolutionFailed"
+)
+
+// target is a cluster the CRDs of a Bootstrap are rolled out to.
+type target struct {
+	name       string
+	kubeConfig *v1alpha1.KubeConfig
+}
+
+// resolveTargets returns the targets of the Bootstrap in the order they are rolled out to.
+func (r *BootstrapReconciler) resolveTargets(ctx context.Context, obj *v1alpha1.Bootstrap) ([]target, error) {
+	spec := obj.Spec.Targets
+	targets := make([]target, 0, len(spec.Clusters))
+
+	for i := range spec.Clusters {
+		targets = append(targets, target{name: spec.Clusters[i].Name, kubeConfig: &spec.Clusters[i].KubeConfig})
+	}
+
+	if spec.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(spec.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse target selector: %w", err)
+		}
+
+		secrets := &corev1.SecretList{}
+		if err := r.List(ctx, secrets, client.InNamespace(obj.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, fmt.Errorf("failed to list target secrets: %w", err)
+		}
+
+		slices.SortFunc(secrets.Items, func(a, b corev1.Secret) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+
+		for _, secret := range secrets.Items {
+			targets = append(targets, target{
+				name: secret.Name,
+				kubeConfig: &v1alpha1.KubeConfig{
+					ServiceAccount: spec.ServiceAccount,
+					SecretRef:      &meta.KubeConfigReference{SecretRef: &meta.SecretKeyReference{Name: secret.Name}},
+				},
+			})
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, errors.New("no target clusters found")
+	}
+
+	seen := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		if seen[t.name] {
+			return nil, fmt.Errorf("duplicate target %s", t.name)
+		}
+
+		seen[t.name] = true
+	}
+
+	return targets, nil
+}
+
+// targetsOutdated returns whether the revision wasn't applied to one of the targets yet.
+func targetsOutdated(obj *v1alpha1.Bootstrap, targets []target, revision string) bool {
+	for _, t := range targets {
+		status := targetStatus(obj, t.name)
+		if status == nil || !status.Ready || status.LastAppliedRevision != revision {
+			return true
+		}
+	}
+
+	return false
+}
+
+// retainTargetStatuses removes the statuses of targets that are no longer defined.
+func retainTargetStatuses(obj *v1alpha1.Bootstrap, targets []target) {
+	obj.Status.Targets = slices.DeleteFunc(obj.Status.Targets, func(status v1alpha1.TargetStatus) bool {
+		return !slices.ContainsFunc(targets, func(t target) bool { return t.name == status.Name })
+	})
+}
+
+// targetStatus returns the last recorded status of the target.
+func targetStatus(obj *v1alpha1.Bootstrap, name string) *v1alpha1.TargetStatus {
+	for i := range obj.Status.Targets {
+		if obj.Status.Targets[i].Name == name {
+			return &obj.Status.Targets[i]
+		}
+	}
+
+	return nil
+}
+
+// reconcileTargets rolls the objects out to the targets and records their status. It returns whether the revision
+// was applied to every target. If it wasn't, because a stage is soaking or the rollout halted at a failed stage, it
+// returns the time until the rollout continues. It returns an error if the objects couldn't be applied to every
+// target without stages.
+func (r *BootstrapReconciler) reconcileTargets(ctx context.Context, obj *v1alpha1.Bootstrap, targets []target, objects []*unstructured.Unstructured, revision string) (time.Duration, bool, error) {
+	if len(obj.Spec.Targets.Stages) > 0 {
+		return r.reconcileStages(ctx, obj, targets, objects, revision)
+	}
+
+	obj.Status.Stages = nil
+
+	statuses := r.rolloutTargets(ctx, obj, targets, objects, revision, false)
+	if err := r.recordTargets(ctx, obj, targets, statuses, revision, ""); err != nil {
+		return 0, false, err
+	}
+
+	return 0, true, nil
+}
+
+// rolloutTargets rolls the objects out to the targets and returns their statuses in the order of the targets.
+// If verify is set, targets that already have the revision are checked for ready CRDs.
+func (r *BootstrapReconciler) rolloutTargets(ctx context.Context, obj *v1alpha1.Bootstrap, targets []target, objects []*unstructured.Unstructured, revision string, verify bool) []v1alpha1.TargetStatus {
+	spec := obj.Spec.Targets
+
+	statuses := rollout(ctx, targets, spec.MaxConcurrent, spec.StopOnFailure, func(ctx context.Context, t target) v1alpha1.TargetStatus {
+		return r.applyToTarget(ctx, obj, t, objects, revision, verify)
+	})
+
+	for i, status := range statuses {
+		if status.Name != "" {
+			continue
+		}
+
+		// The target was skipped, keep what was last applied to it.
+		statuses[i] = v1alpha1.TargetStatus{Name: targets[i].name, Message: "skipped because a previous target failed"}
+		if previous := targetStatus(obj, targets[i].name); previous != nil {
+			statuses[i].LastAppliedRevision = previous.LastAppliedRevision
+		}
+	}
+
+	return statuses
+}
+
+// recordTargets records the statuses of the targets that were rolled out to and keeps the last status of the
+// other targets. It returns an error if one of the targets that were rolled out to failed. If the rollout halted at
+// a stage because of the failure, it's named in the Ready condition.
+func (r *BootstrapReconciler) recordTargets(ctx context.Context, obj *v1alpha1.Bootstrap, targets []target, rolled []v1alpha1.TargetStatus, revision, haltedStage string) error {
+	var (
+		breakingChanges []string
+		failures        []string
+		failed          []v1alpha1.TargetStatus
+	)
+
+	for _, status := range rolled {
+		for _, change := range status.BreakingChanges {
+			breakingChanges = append(breakingChanges, fmt.Sprintf("%s: %s", status.Name, change))
+		}
+
+		if !status.Ready {
+			failures = append(failures, fmt.Sprintf("%s: %s", status.Name, status.Message))
+			failed = append(failed, status)
+		}
+	}
+
+	statuses := make([]v1alpha1.TargetStatus, 0, len(targets))
+
+	for _, t := range targets {
+		i := slices.IndexFunc(rolled, func(status v1alpha1.TargetStatus) bool { return status.Name == t.name })
+		previous := targetStatus(obj, t.name)
+
+		switch {
+		case i >= 0:
+			statuses = append(statuses, rolled[i])
+		case previous != nil:
+			statuses = append(statuses, *previous)
+		default:
+			statuses = append(statuses, v1alpha1.TargetStatus{Name: t.name, Message: "waiting for the previous stages"})
+		}
+	}
+
+	obj.Status.Targets = statuses
+	obj.Status.BreakingChanges = breakingChanges
+
+	if len(failures) > 0 {
+		message := strings.Join(failures, "; ")
+		summary := fmt.Sprintf("failed to apply to %d of %d targets", len(failures), len(targets))
+
+		if haltedStage != "" {
+			summary = fmt.Sprintf("rollout halted at stage %s, %s", haltedStage, summary)
+		}
+
+		r.markFailed(ctx, obj, revision, targetsFailureReason(failed), "%s: %s", summary, message)
+
+		return fmt.Errorf("failed to apply to targets: %s", message)
+	}
+
+	return nil
+}
+
+// targetsFailureReason returns the reason of the Ready condition for the failed targets. Breaking changes take
+// precedence, because they block the upgrade until they are resolved. Otherwise, the reason every failed target
+// shares is used, or targetsFailedReason if they failed for different reasons. Skipped targets have no reason and
+// are ignored.
+func targetsFailureReason(failed []v1alpha1.TargetStatus) string {
+	reason := ""
+
+	for _, status := range failed {
+		switch {
+		case status.Reason == breakingChangeDetectedReason:
+			return breakingChangeDetectedReason
+		case status.Reason == "" || status.Reason == reason:
+		case reason == "":
+			reason = status.Reason
+		default:
+			reason = targetsFailedReason
+		}
+	}
+
+	if reason == "" {
+		return targetsFailedReason
+	}
+
+	return reason
+}
+
+// applyToTarget detects breaking changes against the CRDs in the target and applies the objects to it, unless
+// the revision was already applied. If verify is set and the revision was already applied, it checks that the
+// CRDs are still ready instead.
+func (r *BootstrapReconciler) applyToTarget(ctx context.Context, obj *v1alpha1.Bootstrap, t target, objects []*unstructured.Unstructured, revision string, verify bool) v1alpha1.TargetStatus {
+	logger := log.FromContext(ctx).WithValues("target", t.name)
+	ctx = log.IntoContext(ctx, logger)
+
+	status := v1alpha1.TargetStatus{Name: t.name}
+
+	previous := targetStatus(obj, t.name)
+	upToDate := previous != nil && previous.Ready && previous.LastAppliedRevision == revision
+
+	if upToDate && !verify {
+		return *previous
+	}
+
+	if previous != nil {
+		status.LastAppliedRevision = previous.LastAppliedRevision
+	}
+
+	sm, err := r.newResourceManager(ctx, obj, t.kubeConfig)
+	if err != nil {
+		status.Reason = "ResourceManagerCreateFailed"
+		status.Message = fmt.Sprintf("failed to create resource manager: %s", err)
+
+		return status
+	}
+
+	// The objects are applied to the targets concurrently, so every target gets its own copy.
+	copies := make([]*unstructured.Unstructured, 0, len(objects))
+	for _, o := range objects {
+		copies = append(copies, o.DeepCopy())
+	}
+
+	if upToDate {
+		if err := sm.Wait(copies, ssa.DefaultWaitOptions()); err != nil {
+			status.Reason = "WaitingOnObjectsFailed"
+			status.Message = fmt.Sprintf("CRDs aren't ready: %s", err)
+
+			return status
+		}
+
+		return *previous
+	}
+
+	status.BreakingChanges, status.Reason, err = r.applyObjects(ctx, obj, sm, copies, revision)
+	if err != nil {
+		logger.Error(err, "failed to apply to target")
+		status.Message = err.Error()
+
+		return status
+	}
+
+	status.Ready = true
+	status.LastAppliedRevision = revision
+	status.Message = fmt.Sprintf("applied revision %s", revision)
+
+	return status
+}
+
+// rollout calls apply for the targets in batches of maxConcurrent targets and returns their statuses in the
+// order of the targets. If stopOnFailure is set and a target of a batch fails, the following batches are
+// skipped and their statuses are left empty.
+func rollout(ctx context.Context, targets []target, maxConcurrent int, stopOnFailure bool, apply func(context.Context, target) v1alpha1.TargetStatus) []v1alpha1.TargetStatus {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+
+	statuses := make([]v1alpha1.TargetStatus, len(targets))
+
+	for start := 0; start < len(targets); start += maxConcurrent {
+		end := min(start+maxConcurrent, len(targets))
+
+		var wg sync.WaitGroup
+		for i := start; i < end; i++ {
+			wg.Go(func() {
+				statuses[i] = apply(ctx, targets[i])
+			})
+		}
+
+		wg.Wait()
+
+		if stopOnFailure && slices.ContainsFunc(statuses[start:end], func(s v1alpha1.TargetStatus) bool { return !s.Ready }) {
+			break
+		}
+	}
+
+	return statuses
+}
+
+// pruneTargets deletes the CRDs applied by the Bootstrap from every target.
+func (r *BootstrapReconciler) pruneTargets(ctx context.Context, obj *v1alpha1.Bootstrap) error {
+	targets, err := r.resolveTargets(ctx, obj)
+	if err != nil {
+		return fmt.Errorf("failed to resolve targets: %w", err)
+	}
+
+	for _, t := range targets {
+		sm, err := r.newResourceManager(ctx, obj, t.kubeConfig)
+		if err != nil {
+			r.event(obj, obj.Status.LastAppliedRevision, corev1.EventTypeWarning, "PruneFailed", "failed to create resource manager for target %s: %s", t.name, err)
+
+			return fmt.Errorf("failed to create resource manager for target %s: %w", t.name, err)
+		}
+
+		pruned, err := r.pruneCRDs(ctx, obj, sm.Client())
+		if err != nil {
+			return fmt.Errorf("failed to prune target %s: %w", t.name, err)
+		}
+
+		if len(pruned) > 0 {
+			r.event(obj, obj.Status.LastAppliedRevision, corev1.EventTypeNormal, "CRDsPruned", "pruned CRDs from target %s: %s", t.name, strings.Join(pruned, ", "))
+		}
+	}
+
+	return nil
+}
+
+// selectsTargetSecret returns whether the Bootstrap selects the Secret as a target.
+func selectsTargetSecret(obj *v1alpha1.Bootstrap, secret client.Object) bool {
+	if obj.Spec.Targets == nil || obj.Spec.Targets.Selector == nil || obj.Namespace != secret.GetNamespace() {
+		return false
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(obj.Spec.Targets.Selector)
+	if err != nil {
+		return false
+	}
+
+	return selector.Matches(labels.Set(secret.GetLabels()))
+}
+
+// requestsForTargetSecret enqueues every Bootstrap that selects the changed Secret as a target.
+func (r *BootstrapReconciler) requestsForTargetSecret(ctx context.Context, o client.Object) []reconcile.Request {
+	list := &v1alpha1.BootstrapList{}
+	if err := r.List(ctx, list, client.InNamespace(o.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list bootstraps", "namespace", o.GetNamespace())
+
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for _, item := range list.Items {
+		if selectsTargetSecret(&item, o) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+		}
+	}
+
+	return requests
+}
@@ -0,0 +1,346 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+func newTargetSecret(name, namespace string, labels map[string]string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Data:       map[string][]byte{"value": []byte("kubeconfig")},
+	}
+}
+
+func targetNames(targets []target) []string {
+	names := make([]string, 0, len(targets))
+	for _, t := range targets {
+		names = append(names, t.name)
+	}
+
+	return names
+}
+
+func TestResolveTargets(t *testing.T) {
+	fleet := map[string]string{"fleet": "prod"}
+
+	tests := []struct {
+		name          string
+		targets       v1alpha1.Targets
+		expectedNames []string
+		expectedErr   string
+	}{
+		{
+			name: "clusters in order",
+			targets: v1alpha1.Targets{Clusters: []v1alpha1.Target{
+				{Name: "west"},
+				{Name: "east"},
+			}},
+			expectedNames: []string{"west", "east"},
+		},
+		{
+			name: "selected secrets after clusters sorted by name",
+			targets: v1alpha1.Targets{
+				Clusters: []v1alpha1.Target{{Name: "canary"}},
+				Selector: &metav1.LabelSelector{MatchLabels: fleet},
+			},
+			expectedNames: []string{"canary", "prod-a", "prod-b"},
+		},
+		{
+			name: "duplicate target",
+			targets: v1alpha1.Targets{
+				Clusters: []v1alpha1.Target{{Name: "prod-a"}},
+				Selector: &metav1.LabelSelector{MatchLabels: fleet},
+			},
+			expectedErr: "duplicate target prod-a",
+		},
+		{
+			name:        "no targets selected",
+			targets:     v1alpha1.Targets{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"fleet": "staging"}}},
+			expectedErr: "no target clusters found",
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	r := &BootstrapReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newTargetSecret("prod-b", "default", fleet),
+		newTargetSecret("prod-a", "default", fleet),
+		newTargetSecret("prod-c", "other", fleet),
+		newTargetSecret("unrelated", "default", nil),
+	).Build()}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &v1alpha1.Bootstrap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec:       v1alpha1.BootstrapSpec{Targets: &tt.targets},
+			}
+
+			targets, err := r.resolveTargets(t.Context(), obj)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedNames, targetNames(targets))
+		})
+	}
+}
+
+func TestRollout(t *testing.T) {
+	targets := []target{{name: "a"}, {name: "b"}, {name: "c"}, {name: "d"}, {name: "e"}}
+
+	tests := []struct {
+		name           string
+		maxConcurrent  int
+		stopOnFailure  bool
+		failing        string
+		expectedCalls  int
+		expectedBatch  int
+		expectedStatus []bool
+	}{
+		{
+			name:           "one at a time",
+			expectedCalls:  5,
+			expectedBatch:  1,
+			expectedStatus: []bool{true, true, true, true, true},
+		},
+		{
+			name:           "batches",
+			maxConcurrent:  2,
+			expectedCalls:  5,
+			expectedBatch:  2,
+			expectedStatus: []bool{true, true, true, true, true},
+		},
+		{
+			name:           "continue on failure",
+			maxConcurrent:  2,
+			failing:        "b",
+			expectedCalls:  5,
+			expectedBatch:  2,
+			expectedStatus: []bool{true, false, true, true, true},
+		},
+		{
+			name:           "stop on failure skips the following batches",
+			maxConcurrent:  2,
+			stopOnFailure:  true,
+			failing:        "c",
+			expectedCalls:  4,
+			expectedBatch:  2,
+			expectedStatus: []bool{true, true, false, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				calls    int
+				running  int
+				maxBatch int
+			)
+
+			statuses := rollout(t.Context(), targets, tt.maxConcurrent, tt.stopOnFailure, func(_ context.Context, tg target) v1alpha1.TargetStatus {
+				mu.Lock()
+				calls++
+				running++
+				maxBatch = max(maxBatch, running)
+				mu.Unlock()
+
+				// Give the other targets of the batch a chance to start.
+				time.Sleep(10 * time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+
+				return v1alpha1.TargetStatus{Name: tg.name, Ready: tg.name != tt.failing}
+			})
+
+			ready := make([]bool, 0, len(statuses))
+			for _, s := range statuses {
+				ready = append(ready, s.Ready)
+			}
+
+			assert.Equal(t, tt.expectedCalls, calls)
+			assert.Equal(t, tt.expectedBatch, maxBatch)
+			assert.Equal(t, tt.expectedStatus, ready)
+		})
+	}
+}
+
+func TestReconcileTargets(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	// The kubeconfig Secrets don't exist, so rolling out to any target that isn't up to date fails.
+	r := &BootstrapReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).Build()}
+	kubeConfig := func(name string) v1alpha1.KubeConfig {
+		return v1alpha1.KubeConfig{SecretRef: &meta.KubeConfigReference{SecretRef: &meta.SecretKeyReference{Name: name}}}
+	}
+
+	obj := &v1alpha1.Bootstrap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: v1alpha1.BootstrapSpec{
+			Targets: &v1alpha1.Targets{
+				Clusters: []v1alpha1.Target{
+					{Name: "east", KubeConfig: kubeConfig("east")},
+					{Name: "west", KubeConfig: kubeConfig("west")},
+					{Name: "north", KubeConfig: kubeConfig("north")},
+				},
+				StopOnFailure: true,
+			},
+		},
+		Status: v1alpha1.BootstrapStatus{
+			Targets: []v1alpha1.TargetStatus{
+				{Name: "north", Ready: true, LastAppliedRevision: "v1.0.0"},
+				{Name: "east", Ready: true, LastAppliedRevision: "v1.1.0", Message: "applied revision v1.1.0"},
+			},
+		},
+	}
+
+	targets, err := r.resolveTargets(t.Context(), obj)
+	require.NoError(t, err)
+
+	_, _, err = r.reconcileTargets(t.Context(), obj, targets, []*unstructured.Unstructured{}, "v1.1.0")
+	require.Error(t, err)
+
+	require.Len(t, obj.Status.Targets, 3)
+	assert.Equal(t, v1alpha1.TargetStatus{Name: "east", Ready: true, LastAppliedRevision: "v1.1.0", Message: "applied revision v1.1.0"}, obj.Status.Targets[0])
+	assert.Equal(t, "west", obj.Status.Targets[1].Name)
+	assert.False(t, obj.Status.Targets[1].Ready)
+	assert.Contains(t, obj.Status.Targets[1].Message, "failed to create resource manager")
+	assert.Equal(t, v1alpha1.TargetStatus{Name: "north", LastAppliedRevision: "v1.0.0", Message: "skipped because a previous target failed"}, obj.Status.Targets[2])
+
+	condition := conditions.Get(obj, meta.ReadyCondition)
+	require.NotNil(t, condition)
+	assert.Equal(t, "ResourceManagerCreateFailed", condition.Reason)
+	assert.Contains(t, condition.Message, "failed to apply to 2 of 3 targets")
+}
+
+func TestTargetsFailureReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		failed   []v1alpha1.TargetStatus
+		expected string
+	}{
+		{
+			name: "reason shared by every target",
+			failed: []v1alpha1.TargetStatus{
+				{Name: "east", Reason: "ApplyingCRDSFailed"},
+				{Name: "west", Reason: "ApplyingCRDSFailed"},
+			},
+			expected: "ApplyingCRDSFailed",
+		},
+		{
+			name: "skipped targets are ignored",
+			failed: []v1alpha1.TargetStatus{
+				{Name: "east", Reason: "ApplyingCRDSFailed"},
+				{Name: "west"},
+			},
+			expected: "ApplyingCRDSFailed",
+		},
+		{
+			name: "different reasons",
+			failed: []v1alpha1.TargetStatus{
+				{Name: "east", Reason: "ApplyingCRDSFailed"},
+				{Name: "west", Reason: "ResourceManagerCreateFailed"},
+			},
+			expected: targetsFailedReason,
+		},
+		{
+			name: "breaking changes take precedence",
+			failed: []v1alpha1.TargetStatus{
+				{Name: "east", Reason: "ApplyingCRDSFailed"},
+				{Name: "north", Reason: "ResourceManagerCreateFailed"},
+				{Name: "west", Reason: breakingChangeDetectedReason},
+			},
+			expected: breakingChangeDetectedReason,
+		},
+		{
+			name:     "only skipped targets",
+			failed:   []v1alpha1.TargetStatus{{Name: "east"}},
+			expected: targetsFailedReason,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, targetsFailureReason(tt.failed))
+		})
+	}
+}
+
+func TestReconcileRollsOutToNewTargets(t *testing.T) {
+	tests := []struct {
+		name           string
+		statuses       []v1alpha1.TargetStatus
+		expectedReason string
+	}{
+		{
+			name: "every target up to date",
+			statuses: []v1alpha1.TargetStatus{
+				{Name: "east", Ready: true, LastAppliedRevision: "v1.0.0"},
+				{Name: "west", Ready: true, LastAppliedRevision: "v1.0.0"},
+				{Name: "removed", Ready: true, LastAppliedRevision: "v1.0.0"},
+			},
+			expectedReason: meta.SucceededReason,
+		},
+		{
+			name: "new target",
+			statuses: []v1alpha1.TargetStatus{
+				{Name: "east", Ready: true, LastAppliedRevision: "v1.0.0"},
+			},
+			// The fake source doesn't provide any CRDs, so the rollout fails after fetching them.
+			expectedReason: "ReadingObjectsToApplyFailed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &v1alpha1.Bootstrap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec: v1alpha1.BootstrapSpec{
+					Interval: metav1.Duration{Duration: time.Minute},
+					Targets:  &v1alpha1.Targets{Clusters: []v1alpha1.Target{{Name: "east"}, {Name: "west"}}},
+				},
+				Status: v1alpha1.BootstrapStatus{LastAppliedRevision: "v1.0.0", Targets: tt.statuses},
+			}
+
+			r, _ := newTestReconciler(t, obj)
+
+			_, _ = r.Reconcile(t.Context(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+
+			updated := &v1alpha1.Bootstrap{}
+			require.NoError(t, r.Get(t.Context(), client.ObjectKeyFromObject(obj), updated))
+
+			condition := conditions.Get(updated, meta.ReadyCondition)
+			require.NotNil(t, condition)
+			assert.Equal(t, tt.expectedReason, condition.Reason)
+
+			for _, status := range updated.Status.Targets {
+				assert.NotEqual(t, "removed", status.Name)
+			}
+		})
+	}
+}
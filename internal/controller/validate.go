@@ -0,0 +1,421 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fluxcd/pkg/ssa/utils"
+	corev1 "k8s.io/api/core/v1"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/cel"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	celconfig "k8s.io/apiserver/pkg/apis/cel"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+	"github.com/Skarlso/crd-bootstrap/pkg/source"
+)
+
+// template is a single object which is validated against the CRD of its kind.
+type template struct {
+	// origin describes where the template is coming from for reporting.
+	origin string
+	kind   string
+	// group and version are empty for templates that didn't define an apiVersion. These are
+	// matched by kind only and validated against all versions of the CRD.
+	group   string
+	version string
+	value   *v1.JSON
+}
+
+func (r *BootstrapReconciler) validateObjects(ctx context.Context, obj *v1alpha1.Bootstrap, objects []*unstructured.Unstructured, dir, revision string) error {
+	templates, err := r.collectTemplates(ctx, obj, dir, revision)
+	if err != nil {
+		return fmt.Errorf("failed to collect templates: %w", err)
+	}
+
+	// bail early if there are no templates.
+	if len(templates) == 0 {
+		return nil
+	}
+
+	logger := log.FromContext(ctx)
+
+	crds := make(map[schema.GroupKind]*v1.CustomResourceDefinition, len(objects))
+	// kinds tracks the groups defining a kind, to match templates without an apiVersion.
+	kinds := make(map[string][]schema.GroupKind, len(objects))
+
+	for _, o := range objects {
+		// Create a CRD out of the content.
+		content, err := o.MarshalJSON()
+		if err != nil {
+			return err
+		}
+
+		crd := &v1.CustomResourceDefinition{}
+		if err := yaml.Unmarshal(content, crd); err != nil {
+			return errors.New("failed to unmarshal into custom resource definition")
+		}
+
+		gk := schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}
+		if _, ok := crds[gk]; !ok {
+			kinds[gk.Kind] = append(kinds[gk.Kind], gk)
+		}
+
+		crds[gk] = crd
+	}
+
+	var errs []error
+
+	for _, t := range templates {
+		gk := schema.GroupKind{Group: t.group, Kind: t.kind}
+		if t.version == "" {
+			switch groups := kinds[t.kind]; len(groups) {
+			case 0:
+			case 1:
+				gk = groups[0]
+			default:
+				errs = append(errs, fmt.Errorf("%s of kind %s matches CRDs of multiple groups, set its apiVersion", t.origin, t.kind))
+
+				continue
+			}
+		}
+
+		crd, ok := crds[gk]
+		if !ok {
+			if obj.Spec.StrictTemplates {
+				errs = append(errs, fmt.Errorf("%s of kind %s doesn't match any CRD", t.origin, gk))
+
+				continue
+			}
+
+			logger.Info("skipping template that doesn't match any CRD", "origin", t.origin, "kind", gk.String())
+			r.event(obj, revision, corev1.EventTypeWarning, "TemplateSkipped", "%s of kind %s doesn't match any CRD, skipping it", t.origin, gk)
+
+			continue
+		}
+
+		logger.Info("validating the following object against set template data", "name", crd.GetName(), "origin", t.origin)
+
+		oldTemplate := matchingOldTemplate(obj.Spec.OldTemplate[t.kind], t)
+		found := false
+
+		for _, v := range crd.Spec.Versions {
+			if t.version != "" && t.version != v.Name {
+				continue
+			}
+
+			found = true
+
+			if v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+				continue
+			}
+
+			if err := validateTemplate(ctx, t.kind, v.Schema.OpenAPIV3Schema, t.value, oldTemplate); err != nil {
+				errs = append(errs, fmt.Errorf("failed to validate %s of kind %s against version %s: %w", t.origin, t.kind, v.Name, err))
+			}
+		}
+
+		if !found {
+			errs = append(errs, fmt.Errorf("%s references version %s which isn't defined by CRD %s", t.origin, t.version, crd.GetName()))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// collectTemplates gathers all templates defined inline, in ConfigMaps and in the source.
+func (r *BootstrapReconciler) collectTemplates(ctx context.Context, obj *v1alpha1.Bootstrap, dir, revision string) ([]template, error) {
+	var templates []template
+
+	kinds := make([]string, 0, len(obj.Spec.Template))
+	for k := range obj.Spec.Template {
+		kinds = append(kinds, k)
+	}
+
+	sort.Strings(kinds)
+
+	for _, kind := range kinds {
+		t, err := newTemplate("template "+kind, obj.Spec.Template[kind])
+		if err != nil {
+			return nil, err
+		}
+
+		// the key of the map is always the kind.
+		t.kind = kind
+		templates = append(templates, t)
+	}
+
+	for i, ref := range obj.Spec.Templates {
+		switch {
+		case ref.Inline != nil:
+			t, err := newTemplate(fmt.Sprintf("templates[%d]", i), ref.Inline)
+			if err != nil {
+				return nil, err
+			}
+
+			if t.kind == "" || t.version == "" {
+				return nil, fmt.Errorf("templates[%d] must define apiVersion and kind", i)
+			}
+
+			templates = append(templates, t)
+		case ref.ConfigMapRef != nil:
+			t, err := r.templatesFromConfigMap(ctx, obj.Namespace, ref.ConfigMapRef.Name)
+			if err != nil {
+				return nil, fmt.Errorf("templates[%d]: %w", i, err)
+			}
+
+			templates = append(templates, t...)
+		case ref.SourcePath != "":
+			t, err := r.templatesFromSource(ctx, obj, dir, revision, ref.SourcePath)
+			if err != nil {
+				return nil, fmt.Errorf("templates[%d]: %w", i, err)
+			}
+
+			templates = append(templates, t...)
+		default:
+			return nil, fmt.Errorf("templates[%d] must define one of inline, configMapRef or sourcePath", i)
+		}
+	}
+
+	return templates, nil
+}
+
+func (r *BootstrapReconciler) templatesFromConfigMap(ctx context.Context, namespace, name string) ([]template, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, configMap); err != nil {
+		return nil, fmt.Errorf("failed to find template config map: %w", err)
+	}
+
+	keys := make([]string, 0, len(configMap.Data))
+	for k := range configMap.Data {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var templates []template
+
+	for _, k := range keys {
+		objects, err := utils.ReadObjects(strings.NewReader(configMap.Data[k]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read objects from key %s in config map %s: %w", k, name, err)
+		}
+
+		t, err := templatesFromObjects(fmt.Sprintf("configmap %s/%s", name, k), objects)
+		if err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, t...)
+	}
+
+	return templates, nil
+}
+
+func (r *BootstrapReconciler) templatesFromSource(ctx context.Context, obj *v1alpha1.Bootstrap, dir, revision, path string) ([]template, error) {
+	provider, ok := r.SourceProvider.(source.SampleProvider)
+	if !ok {
+		return nil, errors.New("source provider doesn't support fetching samples")
+	}
+
+	location, err := provider.FetchSamples(ctx, dir, obj, revision, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch samples: %w", err)
+	}
+
+	objects, err := readAllObjects(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read samples: %w", err)
+	}
+
+	return templatesFromObjects("source "+path, objects)
+}
+
+func templatesFromObjects(origin string, objects []*unstructured.Unstructured) ([]template, error) {
+	templates := make([]template, 0, len(objects))
+
+	for _, o := range objects {
+		content, err := o.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal object %s: %w", o.GetName(), err)
+		}
+
+		t, err := newTemplate(fmt.Sprintf("%s %s", origin, o.GetName()), &v1.JSON{Raw: content})
+		if err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, t)
+	}
+
+	return templates, nil
+}
+
+// newTemplate creates a template and sets its kind and version from the content if they are defined.
+func newTemplate(origin string, value *v1.JSON) (template, error) {
+	t := template{origin: origin, value: value}
+	if value == nil || len(value.Raw) == 0 {
+		return t, nil
+	}
+
+	typeMeta := &metav1.TypeMeta{}
+	if err := utiljson.Unmarshal(value.Raw, typeMeta); err != nil {
+		return t, fmt.Errorf("failed to parse %s: %w", origin, err)
+	}
+
+	t.kind = typeMeta.Kind
+
+	if typeMeta.APIVersion != "" {
+		gv, err := schema.ParseGroupVersion(typeMeta.APIVersion)
+		if err != nil {
+			return t, fmt.Errorf("failed to parse apiVersion of %s: %w", origin, err)
+		}
+
+		t.group, t.version = gv.Group, gv.Version
+	}
+
+	return t, nil
+}
+
+// matchingOldTemplate returns the old template if it's for the same version as the template.
+func matchingOldTemplate(oldTemplate *v1.JSON, t template) *v1.JSON {
+	if oldTemplate == nil || t.version == "" {
+		return oldTemplate
+	}
+
+	old, err := newTemplate("old template", oldTemplate)
+	if err != nil || (old.version != "" && old.version != t.version) {
+		return nil
+	}
+
+	return oldTemplate
+}
+
+// validateTemplate validates the template against the OpenAPI schema and evaluates any CEL rules
+// defined through x-kubernetes-validations. Transition rules are only evaluated if an old template
+// is provided, the same way the API server only evaluates them on updates.
+func validateTemplate(ctx context.Context, kind string, schema *v1.JSONSchemaProps, template, oldTemplate *v1.JSON) error {
+	internal := &apiextensions.JSONSchemaProps{}
+	if err := v1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(schema, internal, nil); err != nil {
+		return fmt.Errorf("failed to convert schema: %w", err)
+	}
+
+	value, err := decodeTemplate(template)
+	if err != nil {
+		return fmt.Errorf("failed to decode template: %w", err)
+	}
+
+	eval, _, err := validation.NewSchemaValidator(internal)
+	if err != nil {
+		return err
+	}
+
+	if err := eval.Validate(value).AsError(); err != nil {
+		return err
+	}
+
+	// only schemas with rules need to be structural, the same way the API server only requires it of them.
+	if !hasValidationRules(schema) {
+		return nil
+	}
+
+	structural, err := structuralschema.NewStructural(internal)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to construct structural schema, skipping validation rules", "kind", kind)
+
+		return nil
+	}
+
+	celValidator := cel.NewValidator(structural, true, celconfig.PerCallLimit)
+	if celValidator == nil {
+		return nil
+	}
+
+	oldValue, err := decodeTemplate(oldTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to decode old template: %w", err)
+	}
+
+	errs, _ := celValidator.Validate(ctx, field.NewPath(kind), structural, value, oldValue, celconfig.RuntimeCELCostBudget)
+
+	return errs.ToAggregate()
+}
+
+// hasValidationRules reports whether the schema or any of its nested schemas define x-kubernetes-validations.
+func hasValidationRules(schema *v1.JSONSchemaProps) bool {
+	if schema == nil {
+		return false
+	}
+
+	if len(schema.XValidations) > 0 {
+		return true
+	}
+
+	for _, props := range []map[string]v1.JSONSchemaProps{schema.Properties, schema.PatternProperties, schema.Definitions} {
+		for _, p := range props {
+			if hasValidationRules(&p) {
+				return true
+			}
+		}
+	}
+
+	for _, s := range [][]v1.JSONSchemaProps{schema.AllOf, schema.AnyOf, schema.OneOf} {
+		for _, p := range s {
+			if hasValidationRules(&p) {
+				return true
+			}
+		}
+	}
+
+	if schema.Items != nil {
+		if hasValidationRules(schema.Items.Schema) {
+			return true
+		}
+
+		for _, p := range schema.Items.JSONSchemas {
+			if hasValidationRules(&p) {
+				return true
+			}
+		}
+	}
+
+	if schema.AdditionalProperties != nil && hasValidationRules(schema.AdditionalProperties.Schema) {
+		return true
+	}
+
+	if schema.AdditionalItems != nil && hasValidationRules(schema.AdditionalItems.Schema) {
+		return true
+	}
+
+	return hasValidationRules(schema.Not)
+}
+
+// decodeTemplate returns the unstructured content of a template. Returns nil if the template isn't set.
+func decodeTemplate(template *v1.JSON) (any, error) {
+	if template == nil || len(template.Raw) == 0 {
+		return nil, nil
+	}
+
+	// the api machinery json decoder converts numbers into int64 and float64 the same way the API server does,
+	// which the CEL type checks rely on.
+	var value any
+	if err := utiljson.Unmarshal(template.Raw, &value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
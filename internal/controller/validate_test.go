@@ -0,0 +1,320 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	schema := &apiextensionsv1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"apiVersion": {Type: "string"},
+			"kind":       {Type: "string"},
+			"metadata":   {Type: "object"},
+			"spec": {
+				Type: "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"replicas":    {Type: "integer"},
+					"maxReplicas": {Type: "integer"},
+					"name": {
+						Type: "string",
+						XValidations: apiextensionsv1.ValidationRules{
+							{Rule: "self == oldSelf", Message: "name is immutable"},
+						},
+					},
+				},
+				XValidations: apiextensionsv1.ValidationRules{
+					{Rule: "self.replicas <= self.maxReplicas", Message: "replicas must not exceed maxReplicas"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		template    string
+		oldTemplate string
+		expectedErr string
+	}{
+		{
+			name:     "valid template",
+			template: `{"apiVersion":"example.com/v1","kind":"Test","spec":{"replicas":1,"maxReplicas":2,"name":"a"}}`,
+		},
+		{
+			name:        "schema violation",
+			template:    `{"spec":{"replicas":"one","maxReplicas":2}}`,
+			expectedErr: "spec.replicas",
+		},
+		{
+			name:        "rule violation",
+			template:    `{"spec":{"replicas":3,"maxReplicas":2}}`,
+			expectedErr: "replicas must not exceed maxReplicas",
+		},
+		{
+			name:     "transition rule is skipped without old template",
+			template: `{"spec":{"replicas":1,"maxReplicas":2,"name":"b"}}`,
+		},
+		{
+			name:        "transition rule violation",
+			template:    `{"spec":{"replicas":1,"maxReplicas":2,"name":"b"}}`,
+			oldTemplate: `{"spec":{"replicas":1,"maxReplicas":2,"name":"a"}}`,
+			expectedErr: "name is immutable",
+		},
+		{
+			name:        "transition rule passes",
+			template:    `{"spec":{"replicas":1,"maxReplicas":2,"name":"a"}}`,
+			oldTemplate: `{"spec":{"replicas":1,"maxReplicas":2,"name":"a"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var oldTemplate *apiextensionsv1.JSON
+			if tt.oldTemplate != "" {
+				oldTemplate = &apiextensionsv1.JSON{Raw: []byte(tt.oldTemplate)}
+			}
+
+			err := validateTemplate(t.Context(), "Test", schema, &apiextensionsv1.JSON{Raw: []byte(tt.template)}, oldTemplate)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+}
+
+func TestValidateTemplateWithoutStructuralSchema(t *testing.T) {
+	// patternProperties are valid OpenAPI but not part of structural schemas.
+	schema := &apiextensionsv1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"spec": {
+				Type: "object",
+				PatternProperties: map[string]apiextensionsv1.JSONSchemaProps{
+					"^size$": {Type: "integer"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		template    string
+		expectedErr string
+	}{
+		{
+			name:     "valid template",
+			template: `{"spec":{"size":3}}`,
+		},
+		{
+			name:        "schema violation",
+			template:    `{"spec":{"size":"large"}}`,
+			expectedErr: "spec.size",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTemplate(t.Context(), "Test", schema, &apiextensionsv1.JSON{Raw: []byte(tt.template)}, nil)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestValidateObjects(t *testing.T) {
+	crd := &unstructured.Unstructured{}
+	require.NoError(t, crd.UnmarshalJSON([]byte(`{
+  "apiVersion": "apiextensions.k8s.io/v1",
+  "kind": "CustomResourceDefinition",
+  "metadata": {"name": "tests.example.com"},
+  "spec": {
+    "group": "example.com",
+    "names": {"kind": "Test", "plural": "tests"},
+    "scope": "Namespaced",
+    "versions": [
+      {"name": "v1beta1", "served": true, "storage": false, "schema": {"openAPIV3Schema": {"type": "object", "properties": {
+        "spec": {"type": "object", "properties": {"size": {"type": "string"}}}}}}},
+      {"name": "v1", "served": true, "storage": true, "schema": {"openAPIV3Schema": {"type": "object", "properties": {
+        "spec": {"type": "object", "properties": {"size": {"type": "integer"}}}}}}}
+    ]
+  }
+}`)))
+
+	// other defines the same kind in another group, so it must not replace the CRD of example.com.
+	other := &unstructured.Unstructured{}
+	require.NoError(t, other.UnmarshalJSON([]byte(`{
+  "apiVersion": "apiextensions.k8s.io/v1",
+  "kind": "CustomResourceDefinition",
+  "metadata": {"name": "tests.other.example.com"},
+  "spec": {
+    "group": "other.example.com",
+    "names": {"kind": "Test", "plural": "tests"},
+    "scope": "Namespaced",
+    "versions": [
+      {"name": "v1", "served": true, "storage": true, "schema": {"openAPIV3Schema": {"type": "object", "properties": {
+        "spec": {"type": "object", "properties": {"size": {"type": "string"}}}}}}}
+    ]
+  }
+}`)))
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "templates", Namespace: "default"},
+		Data: map[string]string{
+			"samples.yaml": "apiVersion: example.com/v1beta1\nkind: Test\nmetadata:\n  name: beta\nspec:\n  size: large\n" +
+				"---\napiVersion: example.com/v1\nkind: Test\nmetadata:\n  name: ga\nspec:\n  size: large\n",
+		},
+	}
+
+	tests := []struct {
+		name          string
+		spec          v1alpha1.BootstrapSpec
+		otherGroup    bool
+		expectedErr   []string
+		expectedEvent string
+	}{
+		{
+			name: "template is only validated against its own version",
+			spec: v1alpha1.BootstrapSpec{
+				Templates: []v1alpha1.Template{
+					{Inline: &apiextensionsv1.JSON{Raw: []byte(`{"apiVersion":"example.com/v1beta1","kind":"Test","spec":{"size":"large"}}`)}},
+					{Inline: &apiextensionsv1.JSON{Raw: []byte(`{"apiVersion":"example.com/v1","kind":"Test","spec":{"size":3}}`)}},
+				},
+			},
+		},
+		{
+			name: "legacy template without apiVersion is validated against all versions",
+			spec: v1alpha1.BootstrapSpec{
+				Template: map[string]*apiextensionsv1.JSON{
+					"Test": {Raw: []byte(`{"spec":{"size":"large"}}`)},
+				},
+			},
+			expectedErr: []string{"against version v1:"},
+		},
+		{
+			name: "unknown version is reported",
+			spec: v1alpha1.BootstrapSpec{
+				Templates: []v1alpha1.Template{
+					{Inline: &apiextensionsv1.JSON{Raw: []byte(`{"apiVersion":"example.com/v2","kind":"Test","spec":{}}`)}},
+				},
+			},
+			expectedErr: []string{"references version v2 which isn't defined by CRD tests.example.com"},
+		},
+		{
+			name: "template is validated against the CRD of its group",
+			spec: v1alpha1.BootstrapSpec{
+				Templates: []v1alpha1.Template{
+					{Inline: &apiextensionsv1.JSON{Raw: []byte(`{"apiVersion":"example.com/v1","kind":"Test","spec":{"size":3}}`)}},
+					{Inline: &apiextensionsv1.JSON{Raw: []byte(`{"apiVersion":"other.example.com/v1","kind":"Test","spec":{"size":"large"}}`)}},
+				},
+			},
+			otherGroup: true,
+		},
+		{
+			name: "legacy template matching a kind of multiple groups is reported",
+			spec: v1alpha1.BootstrapSpec{
+				Template: map[string]*apiextensionsv1.JSON{
+					"Test": {Raw: []byte(`{"spec":{"size":3}}`)},
+				},
+			},
+			otherGroup:  true,
+			expectedErr: []string{"template Test of kind Test matches CRDs of multiple groups"},
+		},
+		{
+			name: "template of a group without CRD is skipped",
+			spec: v1alpha1.BootstrapSpec{
+				Templates: []v1alpha1.Template{
+					{Inline: &apiextensionsv1.JSON{Raw: []byte(`{"apiVersion":"exmaple.com/v1","kind":"Test","spec":{}}`)}},
+				},
+			},
+			expectedEvent: "Warning TemplateSkipped templates[0] of kind Test.exmaple.com doesn't match any CRD, skipping it",
+		},
+		{
+			name: "template of a group without CRD is reported with strict templates",
+			spec: v1alpha1.BootstrapSpec{
+				Templates: []v1alpha1.Template{
+					{Inline: &apiextensionsv1.JSON{Raw: []byte(`{"apiVersion":"exmaple.com/v1","kind":"Test","spec":{}}`)}},
+				},
+				StrictTemplates: true,
+			},
+			expectedErr: []string{"templates[0] of kind Test.exmaple.com doesn't match any CRD"},
+		},
+		{
+			name: "legacy template of a kind without CRD is reported with strict templates",
+			spec: v1alpha1.BootstrapSpec{
+				Template: map[string]*apiextensionsv1.JSON{
+					"Tset": {Raw: []byte(`{"spec":{}}`)},
+				},
+				StrictTemplates: true,
+			},
+			expectedErr: []string{"template Tset of kind Tset doesn't match any CRD"},
+		},
+		{
+			name: "templates from config map",
+			spec: v1alpha1.BootstrapSpec{
+				Templates: []v1alpha1.Template{
+					{ConfigMapRef: &corev1.LocalObjectReference{Name: "templates"}},
+				},
+			},
+			expectedErr: []string{"configmap templates/samples.yaml ga of kind Test against version v1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			r := &BootstrapReconciler{
+				Client:        fake.NewClientBuilder().WithObjects(configMap).Build(),
+				EventRecorder: recorder,
+			}
+
+			obj := &v1alpha1.Bootstrap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec:       tt.spec,
+			}
+
+			objects := []*unstructured.Unstructured{crd}
+			if tt.otherGroup {
+				objects = append(objects, other)
+			}
+
+			err := r.validateObjects(t.Context(), obj, objects, t.TempDir(), "v1.0.0")
+			if len(tt.expectedErr) == 0 {
+				require.NoError(t, err)
+
+				if tt.expectedEvent != "" {
+					require.Len(t, recorder.Events, 1)
+					assert.Contains(t, <-recorder.Events, tt.expectedEvent)
+				}
+
+				return
+			}
+
+			require.Error(t, err)
+
+			for _, e := range tt.expectedErr {
+				assert.Contains(t, err.Error(), e)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"time"
+
+	fluxmetrics "github.com/fluxcd/pkg/runtime/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	crtlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "crd_bootstrap"
+
+// Recorder records the metrics of the Bootstrap controller. It extends the GitOps Toolkit recorder, which
+// provides the reconcile condition and duration metrics.
+type Recorder struct {
+	*fluxmetrics.Recorder
+
+	revisionInfo            *prometheus.GaugeVec
+	lastAppliedTimestamp    *prometheus.GaugeVec
+	sourceRequestDuration   *prometheus.HistogramVec
+	sourceRequestErrors     *prometheus.CounterVec
+	breakingChangesDetected *prometheus.CounterVec
+	breakingChangesBlocked  *prometheus.CounterVec
+	validationFailures      *prometheus.CounterVec
+	managedCRDs             *prometheus.GaugeVec
+}
+
+// MustMakeRecorder creates a Recorder and registers its collectors in the controller-runtime metrics registry.
+// It panics if the collectors are already registered.
+func MustMakeRecorder() *Recorder {
+	recorder := NewRecorder()
+	crtlmetrics.Registry.MustRegister(recorder.Collectors()...)
+
+	return recorder
+}
+
+// NewRecorder returns a new Recorder with all metrics configured.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		Recorder: fluxmetrics.NewRecorder(),
+		revisionInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "last_applied_revision_info",
+				Help:      "The last revision applied by a Bootstrap. The value is always 1.",
+			},
+			[]string{"name", "namespace", "source", "revision"},
+		),
+		lastAppliedTimestamp: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "last_applied_timestamp_seconds",
+				Help:      "The unix timestamp of the last successful apply of a Bootstrap.",
+			},
+			[]string{"name", "namespace"},
+		),
+		sourceRequestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "source_request_duration_seconds",
+				Help:      "The duration in seconds of checking a source for updates or fetching CRDs from it.",
+				Buckets:   prometheus.ExponentialBucketsRange(10e-3, 300, 10),
+			},
+			[]string{"source", "host", "operation"},
+		),
+		sourceRequestErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "source_request_errors_total",
+				Help:      "The number of failed requests to check a source for updates or fetch CRDs from it.",
+			},
+			[]string{"source", "host", "operation"},
+		),
+		breakingChangesDetected: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "breaking_changes_detected_total",
+				Help:      "The number of updates that contained breaking schema changes.",
+			},
+			[]string{"name", "namespace"},
+		),
+		breakingChangesBlocked: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "breaking_changes_blocked_total",
+				Help:      "The number of updates that weren't applied because of breaking schema changes.",
+			},
+			[]string{"name", "namespace"},
+		),
+		validationFailures: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "validation_failures_total",
+				Help:      "The number of updates whose CRDs failed to validate the templates.",
+			},
+			[]string{"name", "namespace"},
+		),
+		managedCRDs: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "managed_crds",
+				Help:      "The number of CRDs applied by a Bootstrap.",
+			},
+			[]string{"name", "namespace"},
+		),
+	}
+}
+
+// Collectors returns a slice of Prometheus collectors, which can be used to register them in a metrics registry.
+func (r *Recorder) Collectors() []prometheus.Collector {
+	return append(r.Recorder.Collectors(),
+		r.revisionInfo,
+		r.lastAppliedTimestamp,
+		r.sourceRequestDuration,
+		r.sourceRequestErrors,
+		r.breakingChangesDetected,
+		r.breakingChangesBlocked,
+		r.validationFailures,
+		r.managedCRDs,
+	)
+}
+
+// RecordApplied records a successful apply of the revision and the number of CRDs that were applied.
+func (r *Recorder) RecordApplied(name, namespace, source, revision string, crds int, at time.Time) {
+	r.revisionInfo.DeletePartialMatch(prometheus.Labels{"name": name, "namespace": namespace})
+	r.revisionInfo.WithLabelValues(name, namespace, source, revision).Set(1)
+	r.lastAppliedTimestamp.WithLabelValues(name, namespace).Set(float64(at.Unix()))
+	r.managedCRDs.WithLabelValues(name, namespace).Set(float64(crds))
+}
+
+// RecordSourceRequest records the duration since start of a source operation and whether it failed.
+func (r *Recorder) RecordSourceRequest(source, host, operation string, start time.Time, err error) {
+	r.sourceRequestDuration.WithLabelValues(source, host, operation).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		r.sourceRequestErrors.WithLabelValues(source, host, operation).Inc()
+	}
+}
+
+// RecordBreakingChanges records that breaking changes were detected and whether they blocked the update.
+func (r *Recorder) RecordBreakingChanges(name, namespace string, blocked bool) {
+	r.breakingChangesDetected.WithLabelValues(name, namespace).Inc()
+
+	if blocked {
+		r.breakingChangesBlocked.WithLabelValues(name, namespace).Inc()
+	}
+}
+
+// RecordValidationFailure records that the CRDs of an update failed to validate the templates.
+func (r *Recorder) RecordValidationFailure(name, namespace string) {
+	r.validationFailures.WithLabelValues(name, namespace).Inc()
+}
+
+// Delete removes all metrics of a Bootstrap that was deleted.
+func (r *Recorder) Delete(name, namespace string) {
+	labels := prometheus.Labels{"name": name, "namespace": namespace}
+
+	r.revisionInfo.DeletePartialMatch(labels)
+	r.lastAppliedTimestamp.Delete(labels)
+	r.breakingChangesDetected.Delete(labels)
+	r.breakingChangesBlocked.Delete(labels)
+	r.validationFailures.Delete(labels)
+	r.managedCRDs.Delete(labels)
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordApplied(t *testing.T) {
+	recorder := NewRecorder()
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(recorder.revisionInfo))
+
+	recorder.RecordApplied("test", "default", "github", "v1.0.0", 2, time.Unix(100, 0))
+	recorder.RecordApplied("test", "default", "github", "v1.1.0", 3, time.Unix(200, 0))
+
+	expected := `
+# HELP crd_bootstrap_last_applied_revision_info The last revision applied by a Bootstrap. The value is always 1.
+# TYPE crd_bootstrap_last_applied_revision_info gauge
+crd_bootstrap_last_applied_revision_info{name="test",namespace="default",revision="v1.1.0",source="github"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected)))
+	assert.InDelta(t, 200, testutil.ToFloat64(recorder.lastAppliedTimestamp.WithLabelValues("test", "default")), 0)
+	assert.InDelta(t, 3, testutil.ToFloat64(recorder.managedCRDs.WithLabelValues("test", "default")), 0)
+
+	recorder.Delete("test", "default")
+	assert.Equal(t, 0, testutil.CollectAndCount(recorder.revisionInfo))
+	assert.Equal(t, 0, testutil.CollectAndCount(recorder.managedCRDs))
+}
+
+func TestRecordSourceRequest(t *testing.T) {
+	recorder := NewRecorder()
+
+	recorder.RecordSourceRequest("github", "api.github.com", "check", time.Now(), nil)
+	recorder.RecordSourceRequest("github", "api.github.com", "check", time.Now(), errors.New("boom"))
+
+	assert.Equal(t, 1, testutil.CollectAndCount(recorder.sourceRequestDuration))
+	assert.InDelta(t, 1, testutil.ToFloat64(recorder.sourceRequestErrors.WithLabelValues("github", "api.github.com", "check")), 0)
+}
+
+func TestRecordBreakingChanges(t *testing.T) {
+	recorder := NewRecorder()
+
+	recorder.RecordBreakingChanges("test", "default", false)
+	recorder.RecordBreakingChanges("test", "default", true)
+
+	assert.InDelta(t, 2, testutil.ToFloat64(recorder.breakingChangesDetected.WithLabelValues("test", "default")), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(recorder.breakingChangesBlocked.WithLabelValues("test", "default")), 0)
+}
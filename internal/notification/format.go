@@ -0,0 +1,193 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// slackMessage is the payload of a Slack incoming webhook.
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Fields []slackField `json:"fields,omitempty"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// teamsMessage is the payload of a Microsoft Teams incoming webhook containing an Adaptive Card.
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string         `json:"$schema"`
+	Type    string         `json:"type"`
+	Version string         `json:"version"`
+	Body    []teamsElement `json:"body"`
+}
+
+type teamsElement struct {
+	Type   string      `json:"type"`
+	Text   string      `json:"text,omitempty"`
+	Weight string      `json:"weight,omitempty"`
+	Wrap   bool        `json:"wrap,omitempty"`
+	Facts  []teamsFact `json:"facts,omitempty"`
+}
+
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// ParseFormat returns the Format named by s.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatGeneric, FormatSlack, FormatMSTeams:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown notification format %s", s)
+	}
+}
+
+// formatEvent returns the JSON payload of the event in the given format.
+func formatEvent(format Format, event Event) ([]byte, error) {
+	var payload any
+
+	switch format {
+	case FormatGeneric, "":
+		payload = event
+	case FormatSlack:
+		payload = slackPayload(event)
+	case FormatMSTeams:
+		payload = teamsPayload(event)
+	default:
+		return nil, fmt.Errorf("unknown notification format %s", format)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	return body, nil
+}
+
+// Summary returns a single line describing the event.
+func (e Event) Summary() string {
+	name := e.Bootstrap.String()
+
+	switch e.Reason {
+	case ReasonUpgraded:
+		if e.OldRevision == "" {
+			return fmt.Sprintf("%s installed revision %s", name, e.NewRevision)
+		}
+
+		return fmt.Sprintf("%s upgraded from %s to %s", name, e.OldRevision, e.NewRevision)
+	case ReasonBlocked:
+		return fmt.Sprintf("%s blocked the upgrade to %s because of breaking changes", name, e.NewRevision)
+	default:
+		if e.NewRevision == "" {
+			return fmt.Sprintf("%s failed to reconcile", name)
+		}
+
+		return fmt.Sprintf("%s failed to reconcile revision %s", name, e.NewRevision)
+	}
+}
+
+// String returns the kind and the name of the object, prefixed with the namespace if it has one.
+func (o Object) String() string {
+	kind := o.Kind
+	if kind == "" {
+		kind = "Bootstrap"
+	}
+
+	if o.Namespace == "" {
+		return kind + " " + o.Name
+	}
+
+	return kind + " " + o.Namespace + "/" + o.Name
+}
+
+// facts returns the details of the event as title and value pairs.
+func (e Event) facts() [][2]string {
+	var facts [][2]string
+
+	if e.OldRevision != "" {
+		facts = append(facts, [2]string{"Old revision", e.OldRevision})
+	}
+
+	if e.NewRevision != "" {
+		facts = append(facts, [2]string{"New revision", e.NewRevision})
+	}
+
+	if len(e.CRDs) > 0 {
+		facts = append(facts, [2]string{"CRDs", strings.Join(e.CRDs, ", ")})
+	}
+
+	if len(e.BreakingChanges) > 0 {
+		facts = append(facts, [2]string{"Breaking changes", strings.Join(e.BreakingChanges, "\n")})
+	}
+
+	if e.Error != "" {
+		facts = append(facts, [2]string{"Error", e.Error})
+	}
+
+	return facts
+}
+
+func slackPayload(event Event) slackMessage {
+	color := "danger"
+	if event.Reason == ReasonUpgraded {
+		color = "good"
+	}
+
+	attachment := slackAttachment{Color: color}
+	for _, fact := range event.facts() {
+		attachment.Fields = append(attachment.Fields, slackField{Title: fact[0], Value: fact[1]})
+	}
+
+	return slackMessage{
+		Text:        event.Summary(),
+		Attachments: []slackAttachment{attachment},
+	}
+}
+
+func teamsPayload(event Event) teamsMessage {
+	facts := make([]teamsFact, 0, len(event.facts()))
+	for _, fact := range event.facts() {
+		facts = append(facts, teamsFact{Title: fact[0], Value: fact[1]})
+	}
+
+	return teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{
+			{
+				ContentType: "application/vnd.microsoft.card.adaptive",
+				Content: teamsCard{
+					Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+					Type:    "AdaptiveCard",
+					Version: "1.4",
+					Body: []teamsElement{
+						{Type: "TextBlock", Text: event.Summary(), Weight: "bolder", Wrap: true},
+						{Type: "FactSet", Facts: facts},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,251 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+// Reason describes what happened to a Bootstrap.
+type Reason string
+
+const (
+	// ReasonUpgraded is sent when a new revision was applied.
+	ReasonUpgraded Reason = "Upgraded"
+	// ReasonBlocked is sent when a new revision wasn't applied because of breaking changes.
+	ReasonBlocked Reason = "Blocked"
+	// ReasonFailed is sent when reconciling a Bootstrap failed.
+	ReasonFailed Reason = "Failed"
+)
+
+// Format defines the shape of the payload sent to a webhook.
+type Format string
+
+const (
+	// FormatGeneric sends the Event as JSON.
+	FormatGeneric Format = "generic"
+	// FormatSlack sends a Slack compatible message.
+	FormatSlack Format = "slack"
+	// FormatMSTeams sends a Microsoft Teams compatible message.
+	FormatMSTeams Format = "msteams"
+)
+
+const (
+	// AddressKey is the key in the notification Secret that holds the URL of the webhook.
+	AddressKey = "address"
+	// TokenKey is the key in the notification Secret that holds the HMAC key.
+	TokenKey = "token"
+	// SignatureHeader is the header that contains the HMAC-SHA256 signature of the payload.
+	SignatureHeader = "X-Signature"
+)
+
+const (
+	// RequestTimeout is the timeout of a single attempt to deliver a notification that's recommended for the HTTP
+	// client of the Notifier.
+	RequestTimeout = 10 * time.Second
+	// sendTimeout bounds delivering a notification to a target, including retries.
+	sendTimeout = 30 * time.Second
+	// maxInFlight bounds the number of notifications delivered in the background at the same time.
+	maxInFlight = 64
+)
+
+// ErrTooManyInFlight is reported for notifications that are dropped because too many are being delivered already.
+var ErrTooManyInFlight = errors.New("too many notifications are being delivered, dropping notification")
+
+// Object identifies the Bootstrap or ClusterBootstrap a notification is about.
+type Object struct {
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// Event is the payload sent to generic webhooks.
+type Event struct {
+	Bootstrap       Object    `json:"bootstrap"`
+	Reason          Reason    `json:"reason"`
+	OldRevision     string    `json:"oldRevision,omitempty"`
+	NewRevision     string    `json:"newRevision,omitempty"`
+	CRDs            []string  `json:"crds,omitempty"`
+	BreakingChanges []string  `json:"breakingChanges,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// Target is a webhook that receives notifications.
+type Target struct {
+	Address string
+	Format  Format
+	// HMACKey signs the payload if set.
+	HMACKey []byte
+}
+
+// Notifier sends notifications to the webhook of a Bootstrap and to an optional global webhook.
+type Notifier struct {
+	client client.Client
+	http   *retryablehttp.Client
+	global *Target
+	// timeout bounds sending a notification to a single target.
+	timeout time.Duration
+	// inFlight limits the notifications delivered in the background.
+	inFlight chan struct{}
+}
+
+// NewNotifier creates a Notifier. Requests are sent through c with up to retries retries. c should have a timeout,
+// such as RequestTimeout, and shouldn't be shared with other clients. If global is set, every notification is also
+// sent to it.
+func NewNotifier(kubeClient client.Client, c *http.Client, retries int, global *Target) *Notifier {
+	httpClient := retryablehttp.NewClient()
+	httpClient.HTTPClient = c
+	httpClient.RetryMax = retries
+	httpClient.RetryWaitMin = time.Second
+	httpClient.RetryWaitMax = 5 * time.Second
+	httpClient.Logger = nil
+
+	return &Notifier{
+		client:   kubeClient,
+		http:     httpClient,
+		global:   global,
+		timeout:  sendTimeout,
+		inFlight: make(chan struct{}, maxInFlight),
+	}
+}
+
+// NotifyAsync sends the event like Notify, but in the background, so a slow or hanging webhook doesn't hold up the
+// caller. onFailure is called with the error if the notification couldn't be sent, including if it's dropped
+// because too many notifications are being delivered already.
+func (n *Notifier) NotifyAsync(ctx context.Context, obj *v1alpha1.Bootstrap, event Event, onFailure func(error)) {
+	inFlight := n.inFlight
+
+	select {
+	case inFlight <- struct{}{}:
+	default:
+		onFailure(ErrTooManyInFlight)
+
+		return
+	}
+
+	// the caller keeps modifying the object and may cancel the context once it returns.
+	obj = obj.DeepCopy()
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		defer func() { <-inFlight }()
+
+		if err := n.Notify(ctx, obj, event); err != nil {
+			onFailure(err)
+		}
+	}()
+}
+
+// Notify sends the event to the webhook of the Bootstrap and to the global webhook.
+func (n *Notifier) Notify(ctx context.Context, obj *v1alpha1.Bootstrap, event Event) error {
+	var targets []Target
+
+	if obj.Spec.Notification != nil {
+		target, err := n.targetFor(ctx, obj)
+		if err != nil {
+			return err
+		}
+
+		targets = append(targets, target)
+	}
+
+	if n.global != nil {
+		targets = append(targets, *n.global)
+	}
+
+	var errs []error
+
+	for _, target := range targets {
+		if err := n.Send(ctx, target, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Send formats the event for the target and posts it. Sending is given up if it doesn't succeed within the timeout
+// of the Notifier.
+func (n *Notifier) Send(ctx context.Context, target Target, event Event) (err error) {
+	body, err := formatEvent(target.Format, event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, target.Address, body)
+	if err != nil {
+		return fmt.Errorf("failed to create notification request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(target.HMACKey) > 0 {
+		req.Header.Set(SignatureHeader, Sign(target.HMACKey, body))
+	}
+
+	resp, err := n.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+	}()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("notification webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Sign returns the value of the signature header for the payload.
+func Sign(key, payload []byte) string {
+	mac := hmac.New(sha256.New, key)
+	// Writing to a hash never fails.
+	_, _ = mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (n *Notifier) targetFor(ctx context.Context, obj *v1alpha1.Bootstrap) (Target, error) {
+	secret := &corev1.Secret{}
+	if err := n.client.Get(ctx, types.NamespacedName{Name: obj.Spec.Notification.SecretRef.Name, Namespace: obj.Namespace}, secret); err != nil {
+		return Target{}, fmt.Errorf("failed to find notification secret: %w", err)
+	}
+
+	address, ok := secret.Data[AddressKey]
+	if !ok {
+		return Target{}, fmt.Errorf("notification secret doesn't contain the '%s' key", AddressKey)
+	}
+
+	format := Format(obj.Spec.Notification.Format)
+	if format == "" {
+		format = FormatGeneric
+	}
+
+	return Target{
+		Address: string(bytes.TrimSpace(address)),
+		Format:  format,
+		HMACKey: secret.Data[TokenKey],
+	}, nil
+}
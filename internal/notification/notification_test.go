@@ -0,0 +1,195 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+// request is a request received by the test webhook.
+type request struct {
+	body      []byte
+	signature string
+}
+
+// newWebhook starts a server that fails the first failures requests and records the rest.
+func newWebhook(t *testing.T, failures int32) (*httptest.Server, chan request) {
+	t.Helper()
+
+	var calls atomic.Int32
+
+	received := make(chan request, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		received <- request{body: body, signature: r.Header.Get(SignatureHeader)}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, received
+}
+
+func newTestNotifier(objects ...*corev1.Secret) *Notifier {
+	builder := fake.NewClientBuilder()
+	for _, o := range objects {
+		builder = builder.WithObjects(o)
+	}
+
+	n := NewNotifier(builder.Build(), http.DefaultClient, 2, nil)
+	n.http.RetryWaitMin = time.Millisecond
+	n.http.RetryWaitMax = time.Millisecond
+
+	return n
+}
+
+func TestSendSignsAndRetries(t *testing.T) {
+	server, received := newWebhook(t, 1)
+	n := newTestNotifier()
+
+	event := Event{
+		Bootstrap:   Object{Name: "test", Namespace: "default"},
+		Reason:      ReasonUpgraded,
+		OldRevision: "v1.0.0",
+		NewRevision: "v1.1.0",
+		CRDs:        []string{"tests.example.com"},
+	}
+
+	require.NoError(t, n.Send(t.Context(), Target{Address: server.URL, Format: FormatGeneric, HMACKey: []byte("secret")}, event))
+
+	r := <-received
+	assert.Equal(t, Sign([]byte("secret"), r.body), r.signature)
+
+	var got Event
+	require.NoError(t, json.Unmarshal(r.body, &got))
+	assert.Equal(t, event, got)
+}
+
+func TestSendGivesUp(t *testing.T) {
+	server, _ := newWebhook(t, 10)
+	n := newTestNotifier()
+
+	err := n.Send(t.Context(), Target{Address: server.URL}, Event{Reason: ReasonFailed})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "giving up after 3 attempt(s)")
+}
+
+func TestSendGivesUpOnHangingWebhook(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	n := newTestNotifier()
+	n.timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	err := n.Send(t.Context(), Target{Address: server.URL}, Event{Reason: ReasonFailed})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestFormats(t *testing.T) {
+	event := Event{
+		Bootstrap:       Object{Name: "test", Namespace: "default"},
+		Reason:          ReasonBlocked,
+		OldRevision:     "v1.0.0",
+		NewRevision:     "v2.0.0",
+		BreakingChanges: []string{"field removed"},
+	}
+
+	tests := []struct {
+		name     string
+		format   Format
+		expected string
+	}{
+		{
+			name:   "slack",
+			format: FormatSlack,
+			expected: `{"text":"Bootstrap default/test blocked the upgrade to v2.0.0 because of breaking changes",` +
+				`"attachments":[{"color":"danger","fields":[` +
+				`{"title":"Old revision","value":"v1.0.0","short":false},` +
+				`{"title":"New revision","value":"v2.0.0","short":false},` +
+				`{"title":"Breaking changes","value":"field removed","short":false}]}]}`,
+		},
+		{
+			name:   "msteams",
+			format: FormatMSTeams,
+			expected: `{"type":"message","attachments":[{"contentType":"application/vnd.microsoft.card.adaptive","content":{` +
+				`"$schema":"http://adaptivecards.io/schemas/adaptive-card.json","type":"AdaptiveCard","version":"1.4","body":[` +
+				`{"type":"TextBlock","text":"Bootstrap default/test blocked the upgrade to v2.0.0 because of breaking changes","weight":"bolder","wrap":true},` +
+				`{"type":"FactSet","facts":[{"title":"Old revision","value":"v1.0.0"},{"title":"New revision","value":"v2.0.0"},` +
+				`{"title":"Breaking changes","value":"field removed"}]}]}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := formatEvent(tt.format, event)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(body))
+		})
+	}
+}
+
+func TestNotifyUsesBootstrapAndGlobalTargets(t *testing.T) {
+	bootstrapServer, bootstrapReceived := newWebhook(t, 0)
+	globalServer, globalReceived := newWebhook(t, 0)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: "default"},
+		Data:       map[string][]byte{AddressKey: []byte(bootstrapServer.URL + "\n")},
+	}
+
+	n := newTestNotifier(secret)
+	n.global = &Target{Address: globalServer.URL, Format: FormatSlack}
+
+	obj := &v1alpha1.Bootstrap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: v1alpha1.BootstrapSpec{
+			Notification: &v1alpha1.Notification{SecretRef: corev1.LocalObjectReference{Name: "webhook"}},
+		},
+	}
+
+	require.NoError(t, n.Notify(t.Context(), obj, Event{Bootstrap: Object{Name: "test", Namespace: "default"}, Reason: ReasonFailed}))
+
+	assert.Contains(t, string((<-bootstrapReceived).body), `"reason":"Failed"`)
+	assert.Contains(t, string((<-globalReceived).body), `"text":"Bootstrap default/test failed to reconcile"`)
+}
+
+func TestNotifyAsync(t *testing.T) {
+	server, _ := newWebhook(t, 10)
+	n := newTestNotifier()
+	n.global = &Target{Address: server.URL}
+
+	failures := make(chan error, 1)
+	n.NotifyAsync(t.Context(), &v1alpha1.Bootstrap{}, Event{Reason: ReasonFailed}, func(err error) { failures <- err })
+
+	assert.ErrorContains(t, <-failures, "giving up after 3 attempt(s)")
+
+	// without room for another delivery, the notification is dropped.
+	n.inFlight = make(chan struct{})
+	n.NotifyAsync(t.Context(), &v1alpha1.Bootstrap{}, Event{Reason: ReasonFailed}, func(err error) { failures <- err })
+	assert.ErrorIs(t, <-failures, ErrTooManyInFlight)
+}
@@ -0,0 +1,288 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+	"github.com/Skarlso/crd-bootstrap/pkg/source"
+)
+
+const (
+	// DefaultInterval is the interval used if the Bootstrap doesn't define one.
+	DefaultInterval = 10 * time.Minute
+	// DefaultSemver is the constraint used if a versioned source doesn't define one. It matches every
+	// released version.
+	DefaultSemver = "*"
+)
+
+// SetupBootstrapWebhookWithManager registers the defaulting and validating webhooks for Bootstrap.
+func SetupBootstrapWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, &v1alpha1.Bootstrap{}).
+		WithDefaulter(&BootstrapCustomDefaulter{}).
+		WithValidator(&BootstrapCustomValidator{}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-delivery-crd-bootstrap-v1alpha1-bootstrap,mutating=true,failurePolicy=fail,sideEffects=None,groups=delivery.crd-bootstrap,resources=bootstraps,verbs=create;update,versions=v1alpha1,name=mbootstrap.delivery.crd-bootstrap,admissionReviewVersions=v1
+
+// BootstrapCustomDefaulter sets default values on Bootstrap objects.
+type BootstrapCustomDefaulter struct{}
+
+var _ admission.Defaulter[*v1alpha1.Bootstrap] = &BootstrapCustomDefaulter{}
+
+// Default sets the interval and the semver constraint if they aren't defined.
+func (d *BootstrapCustomDefaulter) Default(_ context.Context, obj *v1alpha1.Bootstrap) error {
+	defaultSpec(&obj.Spec)
+
+	return nil
+}
+
+func defaultSpec(spec *v1alpha1.BootstrapSpec) {
+	if spec.Interval.Duration == 0 {
+		spec.Interval = metav1.Duration{Duration: DefaultInterval}
+	}
+
+	// URL sources are versioned by their digest.
+	if spec.Source != nil && spec.Source.URL == nil && spec.Version.Semver == "" {
+		spec.Version.Semver = DefaultSemver
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-delivery-crd-bootstrap-v1alpha1-bootstrap,mutating=false,failurePolicy=fail,sideEffects=None,groups=delivery.crd-bootstrap,resources=bootstraps,verbs=create;update,versions=v1alpha1,name=vbootstrap.delivery.crd-bootstrap,admissionReviewVersions=v1
+
+// BootstrapCustomValidator validates Bootstrap objects.
+type BootstrapCustomValidator struct{}
+
+var _ admission.Validator[*v1alpha1.Bootstrap] = &BootstrapCustomValidator{}
+
+// ValidateCreate validates the Bootstrap on creation.
+func (v *BootstrapCustomValidator) ValidateCreate(_ context.Context, obj *v1alpha1.Bootstrap) (admission.Warnings, error) {
+	return nil, validateBootstrap(obj)
+}
+
+// ValidateUpdate validates the Bootstrap on update.
+func (v *BootstrapCustomValidator) ValidateUpdate(_ context.Context, _, newObj *v1alpha1.Bootstrap) (admission.Warnings, error) {
+	return nil, validateBootstrap(newObj)
+}
+
+// ValidateDelete doesn't validate anything, deletion is always allowed.
+func (v *BootstrapCustomValidator) ValidateDelete(_ context.Context, _ *v1alpha1.Bootstrap) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func validateBootstrap(obj *v1alpha1.Bootstrap) error {
+	var errs field.ErrorList
+
+	spec := field.NewPath("spec")
+
+	errs = append(errs, validateSource(obj.Spec.Source, spec.Child("source"))...)
+
+	if obj.Spec.Interval.Duration < 0 {
+		errs = append(errs, field.Invalid(spec.Child("interval"), obj.Spec.Interval.Duration.String(), "must be a positive duration"))
+	}
+
+	if obj.Spec.Version.Semver != "" {
+		if _, err := semver.NewConstraint(obj.Spec.Version.Semver); err != nil {
+			errs = append(errs, field.Invalid(spec.Child("version", "semver"), obj.Spec.Version.Semver, err.Error()))
+		}
+	}
+
+	if obj.Spec.Version.Digest != "" && (obj.Spec.Source == nil || obj.Spec.Source.URL == nil) {
+		errs = append(errs, field.Forbidden(spec.Child("version", "digest"), "digest can only be used with a url source"))
+	}
+
+	errs = append(errs, validateTemplates(obj, spec)...)
+	errs = append(errs, validateDependsOn(obj, spec.Child("dependsOn"))...)
+	errs = append(errs, validateTargets(obj, spec.Child("targets"))...)
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	kind := obj.Kind
+	if kind != v1alpha1.ClusterBootstrapKind {
+		kind = "Bootstrap"
+	}
+
+	return apierrors.NewInvalid(v1alpha1.GroupVersion.WithKind(kind).GroupKind(), obj.Name, errs)
+}
+
+func validateSource(src *v1alpha1.Source, path *field.Path) field.ErrorList {
+	defined := source.DefinedTypes(src)
+
+	switch len(defined) {
+	case 0:
+		return field.ErrorList{field.Required(path, "exactly one source must be defined")}
+	case 1:
+		return nil
+	default:
+		return field.ErrorList{field.Invalid(path, defined, "exactly one source must be defined")}
+	}
+}
+
+func validateDependsOn(obj *v1alpha1.Bootstrap, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	for i, dep := range obj.Spec.DependsOn {
+		namespace := dep.Namespace
+		if namespace == "" {
+			namespace = obj.Namespace
+		}
+
+		if dep.Name == obj.Name && namespace == obj.Namespace {
+			errs = append(errs, field.Invalid(path.Index(i), dep.Name, "a bootstrap can't depend on itself"))
+		}
+	}
+
+	return errs
+}
+
+func validateTargets(obj *v1alpha1.Bootstrap, fldPath *field.Path) field.ErrorList {
+	targets := obj.Spec.Targets
+	if targets == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+
+	if obj.Spec.KubeConfig != nil {
+		errs = append(errs, field.Forbidden(fldPath, "targets can't be used together with kubeConfig"))
+	}
+
+	if len(targets.Clusters) == 0 && targets.Selector == nil {
+		errs = append(errs, field.Required(fldPath, "either clusters or a selector must be defined"))
+	}
+
+	if targets.MaxConcurrent < 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("maxConcurrent"), targets.MaxConcurrent, "must be at least 1"))
+	}
+
+	seen := make(map[string]bool, len(targets.Clusters))
+	for i, t := range targets.Clusters {
+		if seen[t.Name] {
+			errs = append(errs, field.Duplicate(fldPath.Child("clusters").Index(i).Child("name"), t.Name))
+		}
+
+		seen[t.Name] = true
+	}
+
+	stages := make(map[string]bool, len(targets.Stages))
+	for i, stage := range targets.Stages {
+		if stages[stage.Name] {
+			errs = append(errs, field.Duplicate(fldPath.Child("stages").Index(i).Child("name"), stage.Name))
+		}
+
+		stages[stage.Name] = true
+
+		if stage.SoakDuration.Duration < 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("stages").Index(i).Child("soakDuration"), stage.SoakDuration.Duration.String(), "must be a positive duration"))
+		}
+
+		for j, pattern := range stage.Targets {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs = append(errs, field.Invalid(fldPath.Child("stages").Index(i).Child("targets").Index(j), pattern, err.Error()))
+			}
+		}
+	}
+
+	return errs
+}
+
+func validateTemplates(obj *v1alpha1.Bootstrap, spec *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	for kind, value := range obj.Spec.Template {
+		errs = append(errs, validateTemplateContent(spec.Child("template").Key(kind), value, false)...)
+	}
+
+	for kind, value := range obj.Spec.OldTemplate {
+		errs = append(errs, validateTemplateContent(spec.Child("oldTemplate").Key(kind), value, false)...)
+	}
+
+	for i, t := range obj.Spec.Templates {
+		path := spec.Child("templates").Index(i)
+
+		set := 0
+
+		if t.Inline != nil {
+			set++
+
+			errs = append(errs, validateTemplateContent(path.Child("inline"), t.Inline, true)...)
+		}
+
+		if t.ConfigMapRef != nil {
+			set++
+		}
+
+		if t.SourcePath != "" {
+			set++
+
+			if obj.Spec.Source != nil && obj.Spec.Source.URL != nil {
+				errs = append(errs, field.Forbidden(path.Child("sourcePath"), "url sources don't provide samples"))
+			}
+		}
+
+		if set != 1 {
+			errs = append(errs, field.Required(path, "exactly one of inline, configMapRef or sourcePath must be defined"))
+		}
+	}
+
+	return errs
+}
+
+// validateTemplateContent makes sure the template is a JSON object with a valid apiVersion. If typed is set,
+// the template must define both apiVersion and kind.
+func validateTemplateContent(path *field.Path, value *apiextensionsv1.JSON, typed bool) field.ErrorList {
+	if value == nil || len(value.Raw) == 0 {
+		return field.ErrorList{field.Required(path, "template must not be empty")}
+	}
+
+	var content map[string]any
+	if err := utiljson.Unmarshal(value.Raw, &content); err != nil {
+		return field.ErrorList{field.Invalid(path, string(value.Raw), fmt.Sprintf("template must be an object: %s", err))}
+	}
+
+	apiVersion, _ := content["apiVersion"].(string)
+	kind, _ := content["kind"].(string)
+
+	if apiVersion != "" {
+		if _, err := schema.ParseGroupVersion(apiVersion); err != nil {
+			return field.ErrorList{field.Invalid(path.Child("apiVersion"), apiVersion, err.Error())}
+		}
+	}
+
+	if typed && (apiVersion == "" || kind == "") {
+		return field.ErrorList{field.Required(path, "template must define apiVersion and kind")}
+	}
+
+	return nil
+}
@@ -0,0 +1,234 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+func TestDefault(t *testing.T) {
+	tests := []struct {
+		name             string
+		spec             v1alpha1.BootstrapSpec
+		expectedInterval time.Duration
+		expectedSemver   string
+	}{
+		{
+			name: "defaults interval and semver",
+			spec: v1alpha1.BootstrapSpec{
+				Source: &v1alpha1.Source{GitHub: &v1alpha1.GitHub{Owner: "o", Repo: "r", Manifest: "m"}},
+			},
+			expectedInterval: DefaultInterval,
+			expectedSemver:   DefaultSemver,
+		},
+		{
+			name: "keeps defined values",
+			spec: v1alpha1.BootstrapSpec{
+				Interval: metav1.Duration{Duration: time.Minute},
+				Source:   &v1alpha1.Source{Helm: &v1alpha1.Helm{ChartReference: "oci://ghcr.io/chart", ChartName: "chart"}},
+				Version:  v1alpha1.Version{Semver: ">=v1"},
+			},
+			expectedInterval: time.Minute,
+			expectedSemver:   ">=v1",
+		},
+		{
+			name: "url source doesn't get a semver",
+			spec: v1alpha1.BootstrapSpec{
+				Source: &v1alpha1.Source{URL: &v1alpha1.URL{URL: "https://example.com/crd.yaml"}},
+			},
+			expectedInterval: DefaultInterval,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &v1alpha1.Bootstrap{Spec: tt.spec}
+
+			require.NoError(t, (&BootstrapCustomDefaulter{}).Default(t.Context(), obj))
+			assert.Equal(t, tt.expectedInterval, obj.Spec.Interval.Duration)
+			assert.Equal(t, tt.expectedSemver, obj.Spec.Version.Semver)
+		})
+	}
+}
+
+func TestValidateCreate(t *testing.T) {
+	github := &v1alpha1.GitHub{Owner: "o", Repo: "r", Manifest: "m"}
+
+	tests := []struct {
+		name        string
+		spec        v1alpha1.BootstrapSpec
+		expectedErr string
+	}{
+		{
+			name: "valid bootstrap",
+			spec: v1alpha1.BootstrapSpec{
+				Source:  &v1alpha1.Source{GitHub: github},
+				Version: v1alpha1.Version{Semver: ">=v1"},
+				Template: map[string]*apiextensionsv1.JSON{
+					"Test": {Raw: []byte(`{"apiVersion":"example.com/v1","spec":{}}`)},
+				},
+			},
+		},
+		{
+			name:        "missing source",
+			spec:        v1alpha1.BootstrapSpec{},
+			expectedErr: "spec.source: Required value",
+		},
+		{
+			name: "empty source",
+			spec: v1alpha1.BootstrapSpec{
+				Source: &v1alpha1.Source{},
+			},
+			expectedErr: "spec.source: Required value",
+		},
+		{
+			name: "multiple sources",
+			spec: v1alpha1.BootstrapSpec{
+				Source: &v1alpha1.Source{GitHub: github, Helm: &v1alpha1.Helm{}},
+			},
+			expectedErr: "exactly one source must be defined",
+		},
+		{
+			name: "invalid semver",
+			spec: v1alpha1.BootstrapSpec{
+				Source:  &v1alpha1.Source{GitHub: github},
+				Version: v1alpha1.Version{Semver: ">=not-a-version"},
+			},
+			expectedErr: "spec.version.semver",
+		},
+		{
+			name: "digest with non url source",
+			spec: v1alpha1.BootstrapSpec{
+				Source:  &v1alpha1.Source{GitHub: github},
+				Version: v1alpha1.Version{Digest: "abc"},
+			},
+			expectedErr: "digest can only be used with a url source",
+		},
+		{
+			name: "depends on itself",
+			spec: v1alpha1.BootstrapSpec{
+				Source:    &v1alpha1.Source{GitHub: github},
+				DependsOn: []v1alpha1.Dependency{{Name: "other"}, {Name: "test", Namespace: "default"}},
+			},
+			expectedErr: "spec.dependsOn[1]: Invalid value: \"test\": a bootstrap can't depend on itself",
+		},
+		{
+			name: "depends on same name in other namespace",
+			spec: v1alpha1.BootstrapSpec{
+				Source:    &v1alpha1.Source{GitHub: github},
+				DependsOn: []v1alpha1.Dependency{{Name: "test", Namespace: "gateway-system"}},
+			},
+		},
+		{
+			name: "targets with kubeconfig",
+			spec: v1alpha1.BootstrapSpec{
+				Source:     &v1alpha1.Source{GitHub: github},
+				KubeConfig: &v1alpha1.KubeConfig{ServiceAccount: "crd-installer"},
+				Targets:    &v1alpha1.Targets{Clusters: []v1alpha1.Target{{Name: "east"}}},
+			},
+			expectedErr: "spec.targets: Forbidden: targets can't be used together with kubeConfig",
+		},
+		{
+			name: "targets without clusters or selector",
+			spec: v1alpha1.BootstrapSpec{
+				Source:  &v1alpha1.Source{GitHub: github},
+				Targets: &v1alpha1.Targets{MaxConcurrent: 2},
+			},
+			expectedErr: "spec.targets: Required value: either clusters or a selector must be defined",
+		},
+		{
+			name: "duplicate target names",
+			spec: v1alpha1.BootstrapSpec{
+				Source:  &v1alpha1.Source{GitHub: github},
+				Targets: &v1alpha1.Targets{Clusters: []v1alpha1.Target{{Name: "east"}, {Name: "west"}, {Name: "east"}}},
+			},
+			expectedErr: "spec.targets.clusters[2].name: Duplicate value: \"east\"",
+		},
+		{
+			name: "duplicate stage names",
+			spec: v1alpha1.BootstrapSpec{
+				Source: &v1alpha1.Source{GitHub: github},
+				Targets: &v1alpha1.Targets{
+					Clusters: []v1alpha1.Target{{Name: "canary"}},
+					Stages:   []v1alpha1.Stage{{Name: "canary", Targets: []string{"canary"}}, {Name: "canary", Targets: []string{"*"}}},
+				},
+			},
+			expectedErr: "spec.targets.stages[1].name: Duplicate value: \"canary\"",
+		},
+		{
+			name: "invalid stage target pattern",
+			spec: v1alpha1.BootstrapSpec{
+				Source: &v1alpha1.Source{GitHub: github},
+				Targets: &v1alpha1.Targets{
+					Clusters: []v1alpha1.Target{{Name: "canary"}},
+					Stages:   []v1alpha1.Stage{{Name: "canary", Targets: []string{"[canary"}}},
+				},
+			},
+			expectedErr: "spec.targets.stages[0].targets[0]",
+		},
+		{
+			name: "negative interval",
+			spec: v1alpha1.BootstrapSpec{
+				Source:   &v1alpha1.Source{GitHub: github},
+				Interval: metav1.Duration{Duration: -time.Second},
+			},
+			expectedErr: "spec.interval",
+		},
+		{
+			name: "unparsable template",
+			spec: v1alpha1.BootstrapSpec{
+				Source: &v1alpha1.Source{GitHub: github},
+				Template: map[string]*apiextensionsv1.JSON{
+					"Test": {Raw: []byte(`"just a string"`)},
+				},
+			},
+			expectedErr: "template must be an object",
+		},
+		{
+			name: "inline template without kind",
+			spec: v1alpha1.BootstrapSpec{
+				Source: &v1alpha1.Source{GitHub: github},
+				Templates: []v1alpha1.Template{
+					{Inline: &apiextensionsv1.JSON{Raw: []byte(`{"apiVersion":"example.com/v1"}`)}},
+				},
+			},
+			expectedErr: "template must define apiVersion and kind",
+		},
+		{
+			name: "template with multiple origins",
+			spec: v1alpha1.BootstrapSpec{
+				Source: &v1alpha1.Source{GitHub: github},
+				Templates: []v1alpha1.Template{
+					{SourcePath: "samples.yaml", ConfigMapRef: &corev1.LocalObjectReference{Name: "samples"}},
+				},
+			},
+			expectedErr: "exactly one of inline, configMapRef or sourcePath must be defined",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &v1alpha1.Bootstrap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec:       tt.spec,
+			}
+
+			_, err := (&BootstrapCustomValidator{}).ValidateCreate(t.Context(), obj)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+// SetupClusterBootstrapWebhookWithManager registers the defaulting and validating webhooks for ClusterBootstrap.
+func SetupClusterBootstrapWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, &v1alpha1.ClusterBootstrap{}).
+		WithDefaulter(&ClusterBootstrapCustomDefaulter{}).
+		WithValidator(&ClusterBootstrapCustomValidator{}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-delivery-crd-bootstrap-v1alpha1-clusterbootstrap,mutating=true,failurePolicy=fail,sideEffects=None,groups=delivery.crd-bootstrap,resources=clusterbootstraps,verbs=create;update,versions=v1alpha1,name=mclusterbootstrap.delivery.crd-bootstrap,admissionReviewVersions=v1
+
+// ClusterBootstrapCustomDefaulter sets default values on ClusterBootstrap objects.
+type ClusterBootstrapCustomDefaulter struct{}
+
+var _ admission.Defaulter[*v1alpha1.ClusterBootstrap] = &ClusterBootstrapCustomDefaulter{}
+
+// Default sets the same defaults as for a Bootstrap.
+func (d *ClusterBootstrapCustomDefaulter) Default(_ context.Context, obj *v1alpha1.ClusterBootstrap) error {
+	defaultSpec(&obj.Spec)
+
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-delivery-crd-bootstrap-v1alpha1-clusterbootstrap,mutating=false,failurePolicy=fail,sideEffects=None,groups=delivery.crd-bootstrap,resources=clusterbootstraps,verbs=create;update,versions=v1alpha1,name=vclusterbootstrap.delivery.crd-bootstrap,admissionReviewVersions=v1
+
+// ClusterBootstrapCustomValidator validates ClusterBootstrap objects.
+type ClusterBootstrapCustomValidator struct{}
+
+var _ admission.Validator[*v1alpha1.ClusterBootstrap] = &ClusterBootstrapCustomValidator{}
+
+// ValidateCreate validates the ClusterBootstrap on creation.
+func (v *ClusterBootstrapCustomValidator) ValidateCreate(_ context.Context, obj *v1alpha1.ClusterBootstrap) (admission.Warnings, error) {
+	return nil, validateClusterBootstrap(obj)
+}
+
+// ValidateUpdate validates the ClusterBootstrap on update.
+func (v *ClusterBootstrapCustomValidator) ValidateUpdate(_ context.Context, _, newObj *v1alpha1.ClusterBootstrap) (admission.Warnings, error) {
+	return nil, validateClusterBootstrap(newObj)
+}
+
+// ValidateDelete doesn't validate anything, deletion is always allowed.
+func (v *ClusterBootstrapCustomValidator) ValidateDelete(_ context.Context, _ *v1alpha1.ClusterBootstrap) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateClusterBootstrap validates the spec the same way as the spec of a Bootstrap. The namespace of the
+// references doesn't matter for validation, so it's left empty.
+func validateClusterBootstrap(obj *v1alpha1.ClusterBootstrap) error {
+	return validateBootstrap(obj.AsBootstrap(""))
+}
@@ -0,0 +1,89 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+func TestClusterBootstrapDefault(t *testing.T) {
+	obj := &v1alpha1.ClusterBootstrap{
+		Spec: v1alpha1.BootstrapSpec{
+			Source: &v1alpha1.Source{GitHub: &v1alpha1.GitHub{Owner: "o", Repo: "r", Manifest: "m"}},
+		},
+	}
+
+	require.NoError(t, (&ClusterBootstrapCustomDefaulter{}).Default(t.Context(), obj))
+	assert.Equal(t, DefaultInterval, obj.Spec.Interval.Duration)
+	assert.Equal(t, DefaultSemver, obj.Spec.Version.Semver)
+}
+
+func TestClusterBootstrapValidateCreate(t *testing.T) {
+	github := &v1alpha1.GitHub{Owner: "o", Repo: "r", Manifest: "m"}
+
+	tests := []struct {
+		name        string
+		spec        v1alpha1.BootstrapSpec
+		expectedErr string
+	}{
+		{
+			name: "valid cluster bootstrap",
+			spec: v1alpha1.BootstrapSpec{
+				Source:  &v1alpha1.Source{GitHub: github},
+				Version: v1alpha1.Version{Semver: ">=v1"},
+			},
+		},
+		{
+			name:        "missing source",
+			spec:        v1alpha1.BootstrapSpec{},
+			expectedErr: `ClusterBootstrap.delivery.crd-bootstrap "test" is invalid: spec.source: Required value`,
+		},
+		{
+			name: "invalid semver",
+			spec: v1alpha1.BootstrapSpec{
+				Source:  &v1alpha1.Source{GitHub: github},
+				Version: v1alpha1.Version{Semver: "not a constraint"},
+			},
+			expectedErr: "spec.version.semver: Invalid value",
+		},
+		{
+			name: "digest on a github source",
+			spec: v1alpha1.BootstrapSpec{
+				Source:  &v1alpha1.Source{GitHub: github},
+				Version: v1alpha1.Version{Digest: "sha256:abc"},
+			},
+			expectedErr: "digest can only be used with a url source",
+		},
+		{
+			name: "depends on itself",
+			spec: v1alpha1.BootstrapSpec{
+				Source:    &v1alpha1.Source{GitHub: github},
+				DependsOn: []v1alpha1.Dependency{{Name: "test"}},
+			},
+			expectedErr: "a bootstrap can't depend on itself",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &v1alpha1.ClusterBootstrap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec:       tt.spec,
+			}
+
+			_, err := (&ClusterBootstrapCustomValidator{}).ValidateCreate(t.Context(), obj)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package source
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+const tempArtifactPrefix = "download-"
+
+type artifactEntry struct {
+	size int64
+	used time.Time
+}
+
+// ArtifactStore caches fetched content on disk under a key, usually made up of the source and the revision, so it
+// doesn't have to be downloaded again. Artifacts are kept across restarts if the directory is. Once the artifacts
+// take up more than the size limit, the least recently used ones are removed. A nil ArtifactStore doesn't store
+// anything.
+type ArtifactStore struct {
+	dir     string
+	maxSize int64
+	now     func() time.Time
+
+	mu      sync.Mutex
+	size    int64
+	entries map[string]artifactEntry
+}
+
+// NewArtifactStore creates an ArtifactStore that keeps up to maxSize bytes of artifacts in dir. A maxSize of zero
+// doesn't limit the size. Artifacts that are already in dir are picked up.
+func NewArtifactStore(dir string, maxSize int64) (*ArtifactStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact directory: %w", err)
+	}
+
+	s := &ArtifactStore{dir: dir, maxSize: maxSize, now: time.Now, entries: make(map[string]artifactEntry)}
+
+	for _, f := range files {
+		if !f.Type().IsRegular() {
+			continue
+		}
+
+		// Leftovers of downloads that were interrupted by a restart.
+		if strings.HasPrefix(f.Name(), tempArtifactPrefix) {
+			if err := os.Remove(filepath.Join(dir, f.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("failed to remove incomplete artifact: %w", err)
+			}
+
+			continue
+		}
+
+		info, err := f.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read artifact %s: %w", f.Name(), err)
+		}
+
+		s.entries[f.Name()] = artifactEntry{size: info.Size(), used: info.ModTime()}
+		s.size += info.Size()
+	}
+
+	if err := s.evict(""); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// ArtifactKey returns the key that the content fetched for the Bootstrap at the revision is stored under. The
+// namespace is part of the key, so content fetched with the credentials of one namespace isn't handed out in another.
+func ArtifactKey(obj *v1alpha1.Bootstrap, revision string) (string, error) {
+	src, err := json.Marshal(obj.Spec.Source)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal source: %w", err)
+	}
+
+	return fmt.Sprintf("%s|%s|%s", obj.Namespace, src, revision), nil
+}
+
+// Put stores the content read from r under the key.
+func (s *ArtifactStore) Put(key string, r io.Reader) error {
+	_, err := s.PutFunc(r, func(string) (string, error) { return key, nil })
+
+	return err
+}
+
+// PutFunc stores the content read from r under the key that keyFunc returns for the sha256 digest of the content,
+// and returns the digest.
+func (s *ArtifactStore) PutFunc(r io.Reader, keyFunc func(digest string) (string, error)) (_ string, err error) {
+	hash := sha256.New()
+
+	if s == nil {
+		if _, err := io.Copy(hash, r); err != nil {
+			return "", fmt.Errorf("failed to hash content: %w", err)
+		}
+
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	temp, err := os.CreateTemp(s.dir, tempArtifactPrefix+"*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create artifact file: %w", err)
+	}
+
+	defer func() {
+		if rerr := os.Remove(temp.Name()); rerr != nil && !errors.Is(rerr, os.ErrNotExist) {
+			err = errors.Join(err, rerr)
+		}
+	}()
+
+	size, err := io.Copy(io.MultiWriter(temp, hash), r)
+	if err != nil {
+		return "", errors.Join(fmt.Errorf("failed to write artifact: %w", err), temp.Close())
+	}
+
+	if err := temp.Close(); err != nil {
+		return "", fmt.Errorf("failed to close artifact file: %w", err)
+	}
+
+	digest := hex.EncodeToString(hash.Sum(nil))
+
+	key, err := keyFunc(digest)
+	if err != nil {
+		return "", err
+	}
+
+	name := artifactName(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Rename(temp.Name(), filepath.Join(s.dir, name)); err != nil {
+		return "", fmt.Errorf("failed to store artifact: %w", err)
+	}
+
+	if previous, ok := s.entries[name]; ok {
+		s.size -= previous.size
+	}
+
+	s.entries[name] = artifactEntry{size: size, used: s.now()}
+	s.size += size
+
+	if err := s.evict(name); err != nil {
+		return "", err
+	}
+
+	return digest, nil
+}
+
+// Copy copies the artifact stored under the key to path. It returns false if there is no such artifact.
+func (s *ArtifactStore) Copy(key, path string) (_ bool, err error) {
+	if s == nil {
+		return false, nil
+	}
+
+	name := artifactName(key)
+
+	// The file is opened while holding the lock, so it can be read even if it's evicted in the meantime.
+	s.mu.Lock()
+
+	entry, ok := s.entries[name]
+	if !ok {
+		s.mu.Unlock()
+
+		return false, nil
+	}
+
+	artifact, err := os.Open(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		s.size -= entry.size
+		delete(s.entries, name)
+		s.mu.Unlock()
+
+		return false, nil
+	}
+
+	if err == nil {
+		entry.used = s.now()
+		s.entries[name] = entry
+
+		// The modification time records the last use, so the order survives restarts.
+		err = os.Chtimes(artifact.Name(), entry.used, entry.used)
+	}
+
+	s.mu.Unlock()
+
+	if err != nil {
+		if artifact != nil {
+			err = errors.Join(err, artifact.Close())
+		}
+
+		return false, fmt.Errorf("failed to open artifact: %w", err)
+	}
+
+	defer func() {
+		if cerr := artifact.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+	}()
+
+	wf, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return false, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	defer func() {
+		if cerr := wf.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+	}()
+
+	if _, err := io.Copy(wf, artifact); err != nil {
+		return false, fmt.Errorf("failed to copy artifact: %w", err)
+	}
+
+	return true, nil
+}
+
+// evict removes the least recently used artifacts until the store fits its size limit. The artifact named keep is
+// never removed, so content that was just stored can be used even if it's larger than the limit on its own.
+func (s *ArtifactStore) evict(keep string) error {
+	if s.maxSize <= 0 || s.size <= s.maxSize {
+		return nil
+	}
+
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		if name != keep {
+			names = append(names, name)
+		}
+	}
+
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Compare(s.entries[a].used.UnixNano(), s.entries[b].used.UnixNano())
+	})
+
+	for _, name := range names {
+		if s.size <= s.maxSize {
+			break
+		}
+
+		if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to evict artifact: %w", err)
+		}
+
+		s.size -= s.entries[name].size
+		delete(s.entries, name)
+	}
+
+	return nil
+}
+
+// artifactName returns the file name of the artifact stored under the key.
+func artifactName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
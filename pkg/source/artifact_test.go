@@ -0,0 +1,123 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// copyArtifact returns the content stored under the key and whether it was found.
+func copyArtifact(t *testing.T, store *ArtifactStore, key string) (string, bool) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "crds.yaml")
+
+	ok, err := store.Copy(key, path)
+	require.NoError(t, err)
+
+	if !ok {
+		return "", false
+	}
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	return string(content), true
+}
+
+func TestArtifactStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	store, err := NewArtifactStore(t.TempDir(), 10)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time {
+		now = now.Add(time.Second)
+
+		return now
+	}
+
+	require.NoError(t, store.Put("a", strings.NewReader("aaaa")))
+	require.NoError(t, store.Put("b", strings.NewReader("bbbb")))
+
+	// Using a makes b the least recently used artifact.
+	content, ok := copyArtifact(t, store, "a")
+	require.True(t, ok)
+	assert.Equal(t, "aaaa", content)
+
+	require.NoError(t, store.Put("c", strings.NewReader("cccc")))
+
+	_, ok = copyArtifact(t, store, "b")
+	assert.False(t, ok)
+
+	_, ok = copyArtifact(t, store, "a")
+	assert.True(t, ok)
+
+	_, ok = copyArtifact(t, store, "c")
+	assert.True(t, ok)
+
+	// An artifact larger than the limit evicts everything else but is kept itself.
+	require.NoError(t, store.Put("d", strings.NewReader("dddddddddddd")))
+
+	for _, key := range []string{"a", "c"} {
+		_, ok = copyArtifact(t, store, key)
+		assert.False(t, ok, key)
+	}
+
+	content, ok = copyArtifact(t, store, "d")
+	require.True(t, ok)
+	assert.Equal(t, "dddddddddddd", content)
+}
+
+func TestArtifactStoreKeepsArtifactsAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewArtifactStore(dir, 0)
+	require.NoError(t, err)
+	require.NoError(t, store.Put("a", strings.NewReader("aaaa")))
+
+	// An interrupted download is cleaned up.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, tempArtifactPrefix+"123"), []byte("partial"), 0o600))
+
+	restarted, err := NewArtifactStore(dir, 0)
+	require.NoError(t, err)
+
+	content, ok := copyArtifact(t, restarted, "a")
+	require.True(t, ok)
+	assert.Equal(t, "aaaa", content)
+
+	_, err = os.Stat(filepath.Join(dir, tempArtifactPrefix+"123"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestArtifactStorePutFunc(t *testing.T) {
+	store, err := NewArtifactStore(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	digest, err := store.PutFunc(strings.NewReader("v1"), func(digest string) (string, error) {
+		return "url|" + digest, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "3bfc269594ef649228e9a74bab00f042efc91d5acc6fbee31a382e80d42388fe", digest)
+
+	content, ok := copyArtifact(t, store, "url|"+digest)
+	require.True(t, ok)
+	assert.Equal(t, "v1", content)
+}
+
+func TestNilArtifactStore(t *testing.T) {
+	var store *ArtifactStore
+
+	digest, err := store.PutFunc(strings.NewReader("v1"), func(digest string) (string, error) {
+		return digest, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "3bfc269594ef649228e9a74bab00f042efc91d5acc6fbee31a382e80d42388fe", digest)
+
+	_, ok := copyArtifact(t, store, digest)
+	assert.False(t, ok)
+}
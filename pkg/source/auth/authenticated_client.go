@@ -2,31 +2,39 @@ package auth
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"net/http"
 
-	"golang.org/x/oauth2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// ConstructAuthenticatedClient creates an authenticated http Client.
-func ConstructAuthenticatedClient(ctx context.Context, client client.Client, name, namespace string) (*http.Client, error) {
+// ConstructAuthenticatedClient creates an http Client that authenticates with the credentials of the secret, using
+// the scheme selected by SchemeFromSecret. Requests are sent through the transport of base with its timeout, if
+// it's set. GitHub App installation tokens are minted with base as well.
+func ConstructAuthenticatedClient(ctx context.Context, client client.Client, base *http.Client, name, namespace string, opts ...Option) (*http.Client, error) {
 	secret := &corev1.Secret{}
 	if err := client.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret); err != nil {
-		return nil, fmt.Errorf("failed to find secret ref for token: %w", err)
+		return nil, fmt.Errorf("failed to find secret ref for credentials: %w", err)
 	}
 
-	token, ok := secret.Data["token"]
-	if !ok {
-		return nil, errors.New("token key not found in provided secret")
+	o := &options{client: base}
+	for _, opt := range opts {
+		opt(o)
 	}
 
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: string(token)},
-	)
+	scheme, err := schemeFromSecret(secret, o)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &http.Client{}
+	if base != nil {
+		*c = *base
+	}
+
+	c.Transport = &Transport{Base: c.Transport, Scheme: scheme}
 
-	return oauth2.NewClient(ctx, ts), nil
+	return c, nil
 }
@@ -0,0 +1,302 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+	"github.com/Skarlso/crd-bootstrap/pkg/source"
+)
+
+const (
+	// tokenRefreshWindow is how long before it expires an installation token is replaced with a new one, so it
+	// doesn't expire during a reconciliation.
+	tokenRefreshWindow = 5 * time.Minute
+	// jwtLifetime is how long the JWT to mint installation tokens with is valid. GitHub accepts at most 10 minutes.
+	jwtLifetime = 9 * time.Minute
+	// jwtClockSkew backdates the JWT to allow for clocks that are behind the clock of GitHub.
+	jwtClockSkew = time.Minute
+)
+
+// GitHubApp authenticates as an installation of a GitHub App. Installation tokens are minted with a JWT signed by
+// the private key of the App.
+type GitHubApp struct {
+	// AppID is the ID of the App.
+	AppID string
+	// InstallationID is the ID of the installation of the App to mint tokens for.
+	InstallationID string
+	// PrivateKey of the App.
+	PrivateKey *rsa.PrivateKey
+	// BaseAPIURL is the GitHub API the tokens are minted with.
+	BaseAPIURL string
+	// Client mints the tokens.
+	Client *http.Client
+	// Tokens caches the tokens. It may be nil.
+	Tokens *InstallationTokens
+
+	keyDigest string
+}
+
+var _ Scheme = &GitHubApp{}
+
+func newGitHubApp(data map[string][]byte, opts *options) (Scheme, error) {
+	if opts.githubAPIURL == "" {
+		return nil, errors.New("GitHub App credentials are only supported by the GitHub source")
+	}
+
+	appID, err := lookup(data, v1alpha1.AppIDKey)
+	if err != nil {
+		return nil, err
+	}
+
+	installationID, err := lookup(data, v1alpha1.InstallationIDKey)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := lookup(data, v1alpha1.PrivateKeyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := parsePrivateKey([]byte(privateKey))
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(privateKey))
+
+	return &GitHubApp{
+		AppID:          appID,
+		InstallationID: installationID,
+		PrivateKey:     key,
+		BaseAPIURL:     opts.githubAPIURL,
+		Client:         opts.client,
+		Tokens:         opts.tokens,
+		keyDigest:      hex.EncodeToString(digest[:]),
+	}, nil
+}
+
+// parsePrivateKey parses a PEM encoded RSA key. GitHub hands out PKCS #1 keys, but PKCS #8 keys work too.
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s key doesn't contain a PEM encoded key", v1alpha1.PrivateKeyKey)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key isn't an RSA key")
+	}
+
+	return key, nil
+}
+
+// Authenticate sends an installation token of the App as bearer token.
+func (a *GitHubApp) Authenticate(req *http.Request) error {
+	token, err := a.Tokens.Token(req.Context(), a)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return nil
+}
+
+// cacheKey identifies the installation tokens of the App. It includes the private key, so a rotated key is used
+// right away, and only holders of the key get the tokens minted with it.
+func (a *GitHubApp) cacheKey() string {
+	return strings.Join([]string{a.BaseAPIURL, a.AppID, a.InstallationID, a.keyDigest}, "|")
+}
+
+// installationToken is an installation token of a GitHub App.
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// mint creates a new installation token.
+func (a *GitHubApp) mint(ctx context.Context) (_ installationToken, err error) {
+	jwt, err := a.jwt(time.Now())
+	if err != nil {
+		return installationToken{}, err
+	}
+
+	tokenURL := fmt.Sprintf("%s/app/installations/%s/access_tokens", strings.TrimSuffix(a.BaseAPIURL, "/"), url.PathEscape(a.InstallationID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, nil)
+	if err != nil {
+		return installationToken{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+
+	c := a.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+
+	res, err := c.Do(req)
+	if err != nil {
+		return installationToken{}, fmt.Errorf("failed to create installation token: %w", err)
+	}
+
+	defer func() {
+		if cerr := res.Body.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+	}()
+
+	if res.StatusCode != http.StatusCreated {
+		return installationToken{}, fmt.Errorf("failed to create installation token: %w", source.NewStatusError(res))
+	}
+
+	var token installationToken
+	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return installationToken{}, fmt.Errorf("failed to decode installation token: %w", err)
+	}
+
+	if token.Token == "" {
+		return installationToken{}, errors.New("GitHub API returned an empty installation token")
+	}
+
+	return token, nil
+}
+
+// jwt returns a JWT that authenticates as the App, signed with RS256.
+func (a *GitHubApp) jwt(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JWT header: %w", err)
+	}
+
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-jwtClockSkew).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": a.AppID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JWT claims: %w", err)
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// InstallationTokens caches installation tokens of GitHub Apps, so every Bootstrap that uses the same App shares
+// them. Tokens are replaced shortly before they expire. A nil InstallationTokens mints a new token every time.
+type InstallationTokens struct {
+	group singleflight.Group
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens map[string]installationToken
+}
+
+// NewInstallationTokens creates an empty InstallationTokens cache.
+func NewInstallationTokens() *InstallationTokens {
+	return &InstallationTokens{now: time.Now, tokens: map[string]installationToken{}}
+}
+
+// Token returns a cached installation token of the App that doesn't expire soon, or mints a new one. Concurrent
+// calls for the same App wait for the same token. Minting isn't cancelled if the context of the caller that started
+// it is.
+func (t *InstallationTokens) Token(ctx context.Context, app *GitHubApp) (string, error) {
+	if t == nil {
+		token, err := app.mint(ctx)
+
+		return token.Token, err
+	}
+
+	key := app.cacheKey()
+
+	if token, ok := t.get(key); ok {
+		return token, nil
+	}
+
+	result, err, _ := t.group.Do(key, func() (any, error) {
+		// Another call might have minted a token between the check above and joining the group.
+		if token, ok := t.get(key); ok {
+			return token, nil
+		}
+
+		token, err := app.mint(context.WithoutCancel(ctx))
+		if err != nil {
+			return "", err
+		}
+
+		t.store(key, token)
+
+		return token.Token, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	token, _ := result.(string)
+
+	return token, nil
+}
+
+func (t *InstallationTokens) get(key string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	token, ok := t.tokens[key]
+	if !ok || !t.now().Add(tokenRefreshWindow).Before(token.ExpiresAt) {
+		return "", false
+	}
+
+	return token.Token, true
+}
+
+// store caches the token and drops expired ones, so tokens of rotated keys, changed installations and deleted
+// Bootstraps don't pile up.
+func (t *InstallationTokens) store(key string, token installationToken) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for k, cached := range t.tokens {
+		if !now.Before(cached.ExpiresAt) {
+			delete(t.tokens, k)
+		}
+	}
+
+	t.tokens[key] = token
+}
@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeGitHubAPI mints installation tokens for installation 42 of App 1234 if the JWT is signed by key.
+func fakeGitHubAPI(t *testing.T, key *rsa.PrivateKey, mints *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/42/access_tokens" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		if err := verifyJWT(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), &key.PublicKey); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+
+			return
+		}
+
+		n := mints.Add(1)
+
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"token":      fmt.Sprintf("ghs_%d", n),
+			"expires_at": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		})
+	}))
+}
+
+func verifyJWT(jwt string, key *rsa.PublicKey) error {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed JWT %q", jwt)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return err
+	}
+
+	var claims struct {
+		IssuedAt  int64  `json:"iat"`
+		ExpiresAt int64  `json:"exp"`
+		Issuer    string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return err
+	}
+
+	if claims.Issuer != "1234" {
+		return fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+
+	if now := time.Now().Unix(); claims.IssuedAt > now || claims.ExpiresAt <= now || claims.ExpiresAt-claims.IssuedAt > 600 {
+		return fmt.Errorf("JWT isn't valid now, iat %d exp %d", claims.IssuedAt, claims.ExpiresAt)
+	}
+
+	return nil
+}
+
+func appSecret(t *testing.T, key *rsa.PrivateKey) *corev1.Secret {
+	t.Helper()
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Data: map[string][]byte{
+			"appID":          []byte("1234"),
+			"installationID": []byte("42"),
+			"privateKey":     pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		},
+	}
+}
+
+func TestGitHubAppInstallationTokens(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var mints atomic.Int32
+
+	server := fakeGitHubAPI(t, key, &mints)
+	defer server.Close()
+
+	now := time.Now()
+	tokens := NewInstallationTokens()
+	tokens.now = func() time.Time { return now }
+
+	scheme, err := SchemeFromSecret(appSecret(t, key), WithGitHubApp(server.URL, tokens))
+	require.NoError(t, err)
+
+	authorization := func() string {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "https://api.github.com/repos/o/r", nil)
+		require.NoError(t, err)
+		require.NoError(t, scheme.Authenticate(req))
+
+		return req.Header.Get("Authorization")
+	}
+
+	assert.Equal(t, "Bearer ghs_1", authorization())
+	assert.Equal(t, "Bearer ghs_1", authorization())
+	assert.Equal(t, int32(1), mints.Load())
+
+	// The token is replaced before it expires.
+	now = now.Add(time.Hour - tokenRefreshWindow)
+
+	assert.Equal(t, "Bearer ghs_2", authorization())
+	assert.Equal(t, int32(2), mints.Load())
+}
+
+func TestGitHubAppInstallationTokensDropExpiredTokens(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var mints atomic.Int32
+
+	server := fakeGitHubAPI(t, key, &mints)
+	defer server.Close()
+
+	now := time.Now()
+	tokens := NewInstallationTokens()
+	tokens.now = func() time.Time { return now }
+	// tokens of a rotated key and of another installation, only one of which expired.
+	tokens.tokens["rotated"] = installationToken{Token: "ghs_rotated", ExpiresAt: now.Add(-time.Minute)}
+	tokens.tokens["other"] = installationToken{Token: "ghs_other", ExpiresAt: now.Add(time.Hour)}
+
+	scheme, err := SchemeFromSecret(appSecret(t, key), WithGitHubApp(server.URL, tokens))
+	require.NoError(t, err)
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "https://api.github.com/repos/o/r", nil)
+	require.NoError(t, err)
+	require.NoError(t, scheme.Authenticate(req))
+
+	assert.Len(t, tokens.tokens, 2)
+	assert.NotContains(t, tokens.tokens, "rotated")
+	assert.Contains(t, tokens.tokens, "other")
+}
+
+func TestGitHubAppWithoutCacheMintsEveryTime(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var mints atomic.Int32
+
+	server := fakeGitHubAPI(t, key, &mints)
+	defer server.Close()
+
+	scheme, err := SchemeFromSecret(appSecret(t, key), WithGitHubApp(server.URL, nil))
+	require.NoError(t, err)
+
+	for range 2 {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		require.NoError(t, scheme.Authenticate(req))
+	}
+
+	assert.Equal(t, int32(2), mints.Load())
+}
+
+func TestGitHubAppErrors(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var mints atomic.Int32
+
+	server := fakeGitHubAPI(t, key, &mints)
+	defer server.Close()
+
+	t.Run("only supported by the github source", func(t *testing.T) {
+		_, err := SchemeFromSecret(appSecret(t, key))
+		require.ErrorContains(t, err, "GitHub App credentials are only supported by the GitHub source")
+	})
+
+	t.Run("invalid private key", func(t *testing.T) {
+		secret := appSecret(t, key)
+		secret.Data["privateKey"] = []byte("not a key")
+
+		_, err := SchemeFromSecret(secret, WithGitHubApp(server.URL, nil))
+		require.ErrorContains(t, err, "privateKey key doesn't contain a PEM encoded key")
+	})
+
+	t.Run("missing installation id", func(t *testing.T) {
+		secret := appSecret(t, key)
+		delete(secret.Data, "installationID")
+
+		_, err := SchemeFromSecret(secret, WithGitHubApp(server.URL, nil))
+		require.ErrorContains(t, err, "installationID key not found")
+	})
+
+	t.Run("rejected JWT", func(t *testing.T) {
+		scheme, err := SchemeFromSecret(appSecret(t, other), WithGitHubApp(server.URL, NewInstallationTokens()))
+		require.NoError(t, err)
+
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		require.ErrorContains(t, scheme.Authenticate(req), "failed to create installation token: unexpected status 401")
+	})
+}
@@ -0,0 +1,263 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+const (
+	// TypeBearer sends the token in the Authorization header as a bearer token.
+	TypeBearer = "bearer"
+	// TypeBasic sends the username and password in the Authorization header.
+	TypeBasic = "basic"
+	// TypeHeader sends the token as the value of the header named by the headerName key, such as PRIVATE-TOKEN for
+	// GitLab or X-JFrog-Art-Api for Artifactory.
+	TypeHeader = "header"
+	// TypeQuery sends the token as the value of the query parameter named by the queryParam key.
+	TypeQuery = "query"
+	// TypeGitHubApp sends an installation token of the GitHub App identified by the appID, installationID and
+	// privateKey keys as a bearer token.
+	TypeGitHubApp = "githubapp"
+)
+
+// Option configures how clients authenticate.
+type Option func(*options)
+
+type options struct {
+	githubAPIURL string
+	tokens       *InstallationTokens
+	client       *http.Client
+}
+
+// WithGitHubApp allows authenticating as a GitHub App installation. Installation tokens are minted through the
+// GitHub API at baseAPIURL and cached in tokens, which may be nil.
+func WithGitHubApp(baseAPIURL string, tokens *InstallationTokens) Option {
+	return func(o *options) {
+		o.githubAPIURL = baseAPIURL
+		o.tokens = tokens
+	}
+}
+
+// Scheme adds credentials to requests.
+type Scheme interface {
+	// Authenticate adds the credentials to the request. The request is a copy that may be modified.
+	Authenticate(req *http.Request) error
+}
+
+// schemes creates the scheme of each auth type from the data of a secret.
+var schemes = map[string]func(data map[string][]byte, opts *options) (Scheme, error){
+	TypeBearer: func(data map[string][]byte, opts *options) (Scheme, error) {
+		token, err := lookup(data, v1alpha1.TokenKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return Bearer{Token: token}, nil
+	},
+	TypeGitHubApp: newGitHubApp,
+	TypeBasic: func(data map[string][]byte, opts *options) (Scheme, error) {
+		username, err := lookup(data, v1alpha1.UsernameKey)
+		if err != nil {
+			return nil, err
+		}
+
+		password, err := lookup(data, v1alpha1.PasswordKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return Basic{Username: username, Password: password}, nil
+	},
+	TypeHeader: func(data map[string][]byte, opts *options) (Scheme, error) {
+		name, err := lookup(data, v1alpha1.HeaderNameKey)
+		if err != nil {
+			return nil, err
+		}
+
+		token, err := lookup(data, v1alpha1.TokenKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return Header{Name: name, Value: token}, nil
+	},
+	TypeQuery: func(data map[string][]byte, opts *options) (Scheme, error) {
+		param, err := lookup(data, v1alpha1.QueryParamKey)
+		if err != nil {
+			return nil, err
+		}
+
+		token, err := lookup(data, v1alpha1.TokenKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return Query{Param: param, Value: token}, nil
+	},
+}
+
+// SchemeFromSecret returns the scheme to authenticate with the credentials of the secret. The scheme is selected by
+// the authType key. Without it, it's inferred from the other keys: an appID selects githubApp, a headerName header, a
+// queryParam query, a username and password basic, and a token on its own bearer.
+func SchemeFromSecret(secret *corev1.Secret, opts ...Option) (Scheme, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return schemeFromSecret(secret, o)
+}
+
+func schemeFromSecret(secret *corev1.Secret, opts *options) (Scheme, error) {
+	authType := strings.ToLower(strings.TrimSpace(string(secret.Data[v1alpha1.AuthTypeKey])))
+	if authType == "" {
+		authType = inferType(secret.Data)
+	}
+
+	newScheme, ok := schemes[authType]
+	if !ok {
+		types := make([]string, 0, len(schemes))
+		for t := range schemes {
+			types = append(types, t)
+		}
+
+		slices.Sort(types)
+
+		return nil, fmt.Errorf("unknown auth type %q, must be one of %s", authType, strings.Join(types, ", "))
+	}
+
+	scheme, err := newScheme(secret.Data, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure %s auth from secret %s: %w", authType, secret.Name, err)
+	}
+
+	return scheme, nil
+}
+
+// inferType returns the auth type of a secret that doesn't define one. A token is sent as bearer token even if the
+// secret also has a username and password, the way secrets were used before other auth types were supported.
+func inferType(data map[string][]byte) string {
+	has := func(key string) bool {
+		_, ok := data[key]
+
+		return ok
+	}
+
+	switch {
+	case has(v1alpha1.AppIDKey):
+		return TypeGitHubApp
+	case has(v1alpha1.HeaderNameKey):
+		return TypeHeader
+	case has(v1alpha1.QueryParamKey):
+		return TypeQuery
+	case has(v1alpha1.UsernameKey) && has(v1alpha1.PasswordKey) && !has(v1alpha1.TokenKey):
+		return TypeBasic
+	default:
+		return TypeBearer
+	}
+}
+
+func lookup(data map[string][]byte, key string) (string, error) {
+	value, ok := data[key]
+	if !ok || len(value) == 0 {
+		return "", fmt.Errorf("%s key not found in provided secret", key)
+	}
+
+	return string(value), nil
+}
+
+// Bearer sends a bearer token in the Authorization header.
+type Bearer struct {
+	Token string
+}
+
+func (b Bearer) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+b.Token)
+
+	return nil
+}
+
+// Basic sends a username and password in the Authorization header.
+type Basic struct {
+	Username string
+	Password string
+}
+
+func (b Basic) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(b.Username, b.Password)
+
+	return nil
+}
+
+// Header sends a token in a custom header.
+type Header struct {
+	Name  string
+	Value string
+}
+
+func (h Header) Authenticate(req *http.Request) error {
+	req.Header.Set(h.Name, h.Value)
+
+	return nil
+}
+
+// Query sends a token in a query parameter.
+type Query struct {
+	Param string
+	Value string
+}
+
+func (q Query) Authenticate(req *http.Request) error {
+	query := req.URL.Query()
+	query.Set(q.Param, q.Value)
+	req.URL.RawQuery = query.Encode()
+
+	return nil
+}
+
+// Transport authenticates requests with a scheme before sending them through Base. Requests that were redirected to
+// another host aren't authenticated, so the credentials don't leak to it.
+type Transport struct {
+	// Base sends the requests.
+	Base http.RoundTripper
+	// Scheme adds the credentials to the requests.
+	Scheme Scheme
+}
+
+var _ http.RoundTripper = &Transport{}
+
+// RoundTrip authenticates the request and sends it.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if redirectedToOtherHost(req) {
+		return base.RoundTrip(req)
+	}
+
+	// A RoundTripper must not modify the request it's given.
+	authenticated := req.Clone(req.Context())
+	if err := t.Scheme.Authenticate(authenticated); err != nil {
+		return nil, fmt.Errorf("failed to authenticate request: %w", err)
+	}
+
+	return base.RoundTrip(authenticated)
+}
+
+// redirectedToOtherHost reports whether the request follows a redirect away from the host of the original request.
+func redirectedToOtherHost(req *http.Request) bool {
+	original := req
+	for original.Response != nil && original.Response.Request != nil {
+		original = original.Response.Request
+	}
+
+	return !strings.EqualFold(original.URL.Host, req.URL.Host)
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestConstructAuthenticatedClient(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          map[string]string
+		expectedAuth  string
+		expectedQuery string
+		expectedExtra map[string]string
+		expectedErr   string
+	}{
+		{
+			name:         "token is sent as bearer token",
+			data:         map[string]string{"token": "secret"},
+			expectedAuth: "Bearer secret",
+		},
+		{
+			name:         "username and password are sent with basic auth",
+			data:         map[string]string{"username": "user", "password": "pass"},
+			expectedAuth: "Basic dXNlcjpwYXNz",
+		},
+		{
+			name:         "token wins over username and password without auth type",
+			data:         map[string]string{"username": "user", "password": "pass", "token": "secret"},
+			expectedAuth: "Bearer secret",
+		},
+		{
+			name:         "explicit basic auth with a token",
+			data:         map[string]string{"authType": "basic", "username": "user", "password": "pass", "token": "secret"},
+			expectedAuth: "Basic dXNlcjpwYXNz",
+		},
+		{
+			name:          "gitlab private token header",
+			data:          map[string]string{"headerName": "PRIVATE-TOKEN", "token": "secret"},
+			expectedExtra: map[string]string{"PRIVATE-TOKEN": "secret"},
+		},
+		{
+			name:          "artifactory api key header",
+			data:          map[string]string{"headerName": "X-JFrog-Art-Api", "token": "secret"},
+			expectedExtra: map[string]string{"X-JFrog-Art-Api": "secret"},
+		},
+		{
+			name:          "token in query parameter",
+			data:          map[string]string{"queryParam": "access_token", "token": "secret"},
+			expectedQuery: "secret",
+		},
+		{
+			name:         "explicit auth type wins over inferred one",
+			data:         map[string]string{"authType": "Bearer", "username": "user", "password": "pass", "token": "secret"},
+			expectedAuth: "Bearer secret",
+		},
+		{
+			name:        "unknown auth type",
+			data:        map[string]string{"authType": "digest", "token": "secret"},
+			expectedErr: `unknown auth type "digest", must be one of basic, bearer, githubapp, header, query`,
+		},
+		{
+			name:        "basic auth without password",
+			data:        map[string]string{"authType": "basic", "username": "user"},
+			expectedErr: "failed to configure basic auth from secret credentials: password key not found",
+		},
+		{
+			name:        "header without token",
+			data:        map[string]string{"headerName": "PRIVATE-TOKEN"},
+			expectedErr: "token key not found",
+		},
+		{
+			name:        "no credentials",
+			data:        map[string]string{},
+			expectedErr: "token key not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received *http.Request
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r
+
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "default"},
+				Data:       map[string][]byte{},
+			}
+			for k, v := range tt.data {
+				secret.Data[k] = []byte(v)
+			}
+
+			kube := fake.NewClientBuilder().WithObjects(secret).Build()
+
+			c, err := ConstructAuthenticatedClient(t.Context(), kube, server.Client(), "credentials", "default")
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL+"/crds.yaml?ref=main", nil)
+			require.NoError(t, err)
+
+			res, err := c.Do(req)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+
+			require.NotNil(t, received)
+			assert.Equal(t, tt.expectedAuth, received.Header.Get("Authorization"))
+			assert.Equal(t, tt.expectedQuery, received.URL.Query().Get("access_token"))
+			assert.Equal(t, "main", received.URL.Query().Get("ref"))
+
+			for k, v := range tt.expectedExtra {
+				assert.Equal(t, v, received.Header.Get(k))
+			}
+
+			// The request of the caller isn't modified.
+			assert.Empty(t, req.Header)
+			assert.Equal(t, "ref=main", req.URL.RawQuery)
+		})
+	}
+}
+
+func TestTransportDoesNotAuthenticateRedirectsToOtherHosts(t *testing.T) {
+	var (
+		storageAuth  string
+		storageQuery string
+	)
+
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		storageAuth = r.Header.Get("X-JFrog-Art-Api") + r.Header.Get("Authorization")
+		storageQuery = r.URL.RawQuery
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storage.Close()
+
+	var originAuth string
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		originAuth = r.Header.Get("X-JFrog-Art-Api")
+
+		http.Redirect(w, r, storage.URL+"/blob?signature=abc", http.StatusFound)
+	}))
+	defer origin.Close()
+
+	c := &http.Client{Transport: &Transport{Scheme: Header{Name: "X-JFrog-Art-Api", Value: "secret"}}}
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, origin.URL, nil)
+	require.NoError(t, err)
+
+	res, err := c.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	assert.Equal(t, "secret", originAuth)
+	assert.Empty(t, storageAuth)
+	assert.Equal(t, "signature=abc", storageQuery)
+}
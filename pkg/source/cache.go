@@ -0,0 +1,126 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// CacheKey identifies a version lookup. Bootstraps that look up versions with the same key share the result.
+type CacheKey struct {
+	// Type of the source.
+	Type Type
+	// Endpoint is the API or repository URL the versions are looked up at.
+	Endpoint string
+	// Name identifies the repository or chart at the endpoint.
+	Name string
+	// Credentials identifies the credentials used for the lookup, such as the namespace and name of a Secret.
+	// It's empty for anonymous lookups.
+	Credentials string
+	// Transport identifies the transport settings used for the lookup, see TransportKey.
+	Transport string
+}
+
+// String returns the key as a single string.
+func (k CacheKey) String() string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s", k.Type, k.Endpoint, k.Name, k.Credentials, k.Transport)
+}
+
+type versionEntry struct {
+	versions []string
+	expires  time.Time
+}
+
+// VersionCache caches discovered versions for a TTL and shares a single lookup between concurrent callers with the
+// same key. Failed lookups aren't cached. A nil VersionCache or one with a TTL of zero doesn't cache anything.
+type VersionCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	group singleflight.Group
+
+	mu      sync.Mutex
+	entries map[CacheKey]versionEntry
+}
+
+// NewVersionCache creates a VersionCache that keeps versions for ttl.
+func NewVersionCache(ttl time.Duration) *VersionCache {
+	return &VersionCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[CacheKey]versionEntry),
+	}
+}
+
+// Versions returns the cached versions for the key, or calls discover to look them up. Concurrent calls with the
+// same key wait for the same lookup. The lookup isn't cancelled if the context of the caller that started it is.
+func (c *VersionCache) Versions(ctx context.Context, key CacheKey, discover func(context.Context) ([]string, error)) ([]string, error) {
+	if c == nil || c.ttl <= 0 {
+		return discover(ctx)
+	}
+
+	if versions, ok := c.get(key); ok {
+		return versions, nil
+	}
+
+	result, err, _ := c.group.Do(key.String(), func() (any, error) {
+		// Another lookup might have finished between the check above and joining the group.
+		if versions, ok := c.get(key); ok {
+			return versions, nil
+		}
+
+		versions, err := discover(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		c.set(key, versions)
+
+		return versions, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	versions, _ := result.([]string)
+
+	return slices.Clone(versions), nil
+}
+
+func (c *VersionCache) get(key CacheKey) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+
+		return nil, false
+	}
+
+	return slices.Clone(entry.versions), true
+}
+
+func (c *VersionCache) set(key CacheKey, versions []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+
+	// Drop expired entries, so keys that are no longer looked up don't pile up.
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = versionEntry{versions: slices.Clone(versions), expires: now.Add(c.ttl)}
+}
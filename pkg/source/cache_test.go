@@ -0,0 +1,141 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionCache(t *testing.T) {
+	key := CacheKey{Type: GitHub, Endpoint: "https://api.github.com", Name: "owner/repo"}
+
+	tests := []struct {
+		name          string
+		cache         func() *VersionCache
+		advance       time.Duration
+		keys          []CacheKey
+		expectedCalls int
+	}{
+		{
+			name:          "cached within ttl",
+			cache:         func() *VersionCache { return NewVersionCache(time.Minute) },
+			advance:       30 * time.Second,
+			keys:          []CacheKey{key, key},
+			expectedCalls: 1,
+		},
+		{
+			name:          "expired after ttl",
+			cache:         func() *VersionCache { return NewVersionCache(time.Minute) },
+			advance:       time.Minute,
+			keys:          []CacheKey{key, key},
+			expectedCalls: 2,
+		},
+		{
+			name:          "different credentials aren't shared",
+			cache:         func() *VersionCache { return NewVersionCache(time.Minute) },
+			keys:          []CacheKey{key, {Type: GitHub, Endpoint: key.Endpoint, Name: key.Name, Credentials: "default/token"}},
+			expectedCalls: 2,
+		},
+		{
+			name:          "different transport settings aren't shared",
+			cache:         func() *VersionCache { return NewVersionCache(time.Minute) },
+			keys:          []CacheKey{key, {Type: GitHub, Endpoint: key.Endpoint, Name: key.Name, Transport: "cert=default/ca"}},
+			expectedCalls: 2,
+		},
+		{
+			name:          "disabled with zero ttl",
+			cache:         func() *VersionCache { return NewVersionCache(0) },
+			keys:          []CacheKey{key, key},
+			expectedCalls: 2,
+		},
+		{
+			name:          "nil cache",
+			cache:         func() *VersionCache { return nil },
+			keys:          []CacheKey{key, key},
+			expectedCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := tt.cache()
+
+			now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			if cache != nil {
+				cache.now = func() time.Time { return now }
+			}
+
+			calls := 0
+			discover := func(context.Context) ([]string, error) {
+				calls++
+
+				return []string{"v1.1.0", "v1.0.0"}, nil
+			}
+
+			for _, k := range tt.keys {
+				versions, err := cache.Versions(t.Context(), k, discover)
+				require.NoError(t, err)
+				assert.Equal(t, []string{"v1.1.0", "v1.0.0"}, versions)
+
+				// Callers must not be able to change the cached versions.
+				versions[0] = "changed"
+				now = now.Add(tt.advance)
+			}
+
+			assert.Equal(t, tt.expectedCalls, calls)
+		})
+	}
+}
+
+func TestVersionCacheDoesNotCacheErrors(t *testing.T) {
+	cache := NewVersionCache(time.Minute)
+	key := CacheKey{Type: Helm, Endpoint: "https://charts.example.com", Name: "chart"}
+
+	_, err := cache.Versions(t.Context(), key, func(context.Context) ([]string, error) {
+		return nil, errors.New("unavailable")
+	})
+	require.EqualError(t, err, "unavailable")
+
+	versions, err := cache.Versions(t.Context(), key, func(context.Context) ([]string, error) {
+		return []string{"1.0.0"}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0"}, versions)
+}
+
+func TestVersionCacheSharesConcurrentLookups(t *testing.T) {
+	cache := NewVersionCache(time.Minute)
+	key := CacheKey{Type: GitLab, Endpoint: "https://gitlab.com", Name: "group/project"}
+
+	var calls atomic.Int32
+
+	release := make(chan struct{})
+	discover := func(context.Context) ([]string, error) {
+		calls.Add(1)
+		<-release
+
+		return []string{"v1.0.0"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			versions, err := cache.Versions(t.Context(), key, discover)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"v1.0.0"}, versions)
+		})
+	}
+
+	// Give every caller a chance to join the lookup before it finishes.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+}
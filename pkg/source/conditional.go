@@ -0,0 +1,155 @@
+package source
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultConditionalCacheTTL is how long the validators of a response are remembered after they were last used. It's
+// longer than the interval of most Bootstraps, so their requests keep finding them.
+const DefaultConditionalCacheTTL = time.Hour
+
+type conditionalEntry struct {
+	etag         string
+	lastModified string
+	result       any
+	expires      time.Time
+}
+
+// ConditionalCache remembers the ETag and Last-Modified validators of responses together with the result that was
+// read from them. They are sent with the next request for the same URL, so an unchanged response isn't downloaded
+// again. Validators that weren't used for the TTL are dropped, so URLs and credentials that are no longer requested,
+// such as those of deleted Bootstraps or rotated Secrets, don't pile up. A nil ConditionalCache or one with a TTL of
+// zero sends plain requests.
+type ConditionalCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]conditionalEntry
+}
+
+// NewConditionalCache creates an empty ConditionalCache that keeps validators for ttl after they were last used.
+func NewConditionalCache(ttl time.Duration) *ConditionalCache {
+	return &ConditionalCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]conditionalEntry),
+	}
+}
+
+// Conditional sends the request with the validators of the last response for its URL, credentials and transport. If
+// the server responds with 304 Not Modified, the result read from the last response is returned without reading the
+// body. Otherwise, read is called with the response, and its result is remembered if the response has any validators.
+// Credentials and transport identify the credentials and the transport settings, such as returned by TransportKey,
+// the request is sent with, so responses aren't shared between them.
+func Conditional[T any](c *ConditionalCache, client *http.Client, req *http.Request, credentials, transport string, read func(*http.Response) (T, error)) (_ T, err error) {
+	var zero T
+
+	key := credentials + "|" + transport + "|" + req.URL.String()
+
+	entry, cached := c.get(key)
+	if cached {
+		if entry.etag != "" {
+			req.Header.Set("If-None-Match", entry.etag)
+		}
+
+		if entry.lastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.lastModified)
+		}
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return zero, err
+	}
+
+	defer func() {
+		if cerr := res.Body.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+	}()
+
+	if res.StatusCode == http.StatusNotModified {
+		result, ok := entry.result.(T)
+		if !cached || !ok {
+			return zero, fmt.Errorf("unexpected status code %d for a request without validators", res.StatusCode)
+		}
+
+		// Drain what's left of the body, so the connection can be reused.
+		_, _ = io.Copy(io.Discard, res.Body)
+
+		return result, nil
+	}
+
+	result, err := read(res)
+	if err != nil {
+		return zero, err
+	}
+
+	c.set(key, conditionalEntry{
+		etag:         res.Header.Get("ETag"),
+		lastModified: res.Header.Get("Last-Modified"),
+		result:       result,
+	})
+
+	return result, nil
+}
+
+// get returns the entry of the key and keeps it for another TTL, because it's about to be used.
+func (c *ConditionalCache) get(key string) (conditionalEntry, bool) {
+	if c == nil || c.ttl <= 0 {
+		return conditionalEntry{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return conditionalEntry{}, false
+	}
+
+	now := c.now()
+	if !now.Before(entry.expires) {
+		delete(c.entries, key)
+
+		return conditionalEntry{}, false
+	}
+
+	entry.expires = now.Add(c.ttl)
+	c.entries[key] = entry
+
+	return entry, true
+}
+
+func (c *ConditionalCache) set(key string, entry conditionalEntry) {
+	if c == nil || c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+
+	// Drop expired entries, so keys that are no longer requested don't pile up.
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	if entry.etag == "" && entry.lastModified == "" {
+		delete(c.entries, key)
+
+		return
+	}
+
+	entry.expires = now.Add(c.ttl)
+	c.entries[key] = entry
+}
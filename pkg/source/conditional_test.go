@@ -0,0 +1,193 @@
+package source
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditional(t *testing.T) {
+	tests := []struct {
+		name          string
+		cache         *ConditionalCache
+		header        string
+		value         string
+		condition     string
+		credentials   []string
+		transports    []string
+		expectedReads int
+	}{
+		{
+			name:          "etag",
+			cache:         NewConditionalCache(time.Minute),
+			header:        "ETag",
+			value:         `"v1"`,
+			condition:     "If-None-Match",
+			credentials:   []string{"", ""},
+			expectedReads: 1,
+		},
+		{
+			name:          "last modified",
+			cache:         NewConditionalCache(time.Minute),
+			header:        "Last-Modified",
+			value:         "Mon, 01 Jan 2024 12:00:00 GMT",
+			condition:     "If-Modified-Since",
+			credentials:   []string{"", ""},
+			expectedReads: 1,
+		},
+		{
+			name:          "no validators",
+			cache:         NewConditionalCache(time.Minute),
+			credentials:   []string{"", ""},
+			expectedReads: 2,
+		},
+		{
+			name:          "different credentials",
+			cache:         NewConditionalCache(time.Minute),
+			header:        "ETag",
+			value:         `"v1"`,
+			condition:     "If-None-Match",
+			credentials:   []string{"", "default/token"},
+			expectedReads: 2,
+		},
+		{
+			name:          "different transports",
+			cache:         NewConditionalCache(time.Minute),
+			header:        "ETag",
+			value:         `"v1"`,
+			condition:     "If-None-Match",
+			credentials:   []string{"", ""},
+			transports:    []string{"", "cert=default/ca"},
+			expectedReads: 2,
+		},
+		{
+			name:          "disabled with zero ttl",
+			cache:         NewConditionalCache(0),
+			header:        "ETag",
+			value:         `"v1"`,
+			condition:     "If-None-Match",
+			credentials:   []string{"", ""},
+			expectedReads: 2,
+		},
+		{
+			name:          "nil cache",
+			header:        "ETag",
+			value:         `"v1"`,
+			condition:     "If-None-Match",
+			credentials:   []string{"", ""},
+			expectedReads: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.condition != "" && r.Header.Get(tt.condition) == tt.value {
+					w.WriteHeader(http.StatusNotModified)
+
+					return
+				}
+
+				if tt.header != "" {
+					w.Header().Set(tt.header, tt.value)
+				}
+
+				_, _ = w.Write([]byte("content"))
+			}))
+			defer server.Close()
+
+			reads := 0
+			read := func(res *http.Response) (string, error) {
+				reads++
+
+				content, err := io.ReadAll(res.Body)
+
+				return string(content), err
+			}
+
+			for i, credentials := range tt.credentials {
+				req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL, nil)
+				require.NoError(t, err)
+
+				var transport string
+				if tt.transports != nil {
+					transport = tt.transports[i]
+				}
+
+				content, err := Conditional(tt.cache, server.Client(), req, credentials, transport, read)
+				require.NoError(t, err)
+				assert.Equal(t, "content", content)
+			}
+
+			assert.Equal(t, tt.expectedReads, reads)
+		})
+	}
+}
+
+func TestConditionalDoesNotRememberErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	cache := NewConditionalCache(time.Minute)
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	_, err = Conditional(cache, server.Client(), req, "", "", func(*http.Response) (string, error) {
+		return "", errors.New("invalid content")
+	})
+	require.EqualError(t, err, "invalid content")
+
+	req, err = http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	content, err := Conditional(cache, server.Client(), req, "", "", func(res *http.Response) (string, error) {
+		content, err := io.ReadAll(res.Body)
+
+		return string(content), err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "content", content)
+}
+
+func TestConditionalCacheEvictsUnusedEntries(t *testing.T) {
+	now := time.Now()
+	cache := NewConditionalCache(time.Hour)
+	cache.now = func() time.Time { return now }
+
+	cache.set("a", conditionalEntry{etag: `"a"`})
+	cache.set("b", conditionalEntry{etag: `"b"`})
+
+	// Using an entry keeps it for another TTL.
+	now = now.Add(30 * time.Minute)
+	_, ok := cache.get("a")
+	require.True(t, ok)
+
+	// Setting an entry drops the ones that weren't used for the TTL.
+	now = now.Add(45 * time.Minute)
+	cache.set("c", conditionalEntry{etag: `"c"`})
+
+	assert.Len(t, cache.entries, 2)
+	assert.Contains(t, cache.entries, "a")
+	assert.Contains(t, cache.entries, "c")
+
+	now = now.Add(time.Hour)
+	_, ok = cache.get("a")
+	assert.False(t, ok)
+	assert.NotContains(t, cache.entries, "a")
+}
@@ -23,28 +23,22 @@ var (
 // Source defines a source that can fetch CRD data from a config map.
 type Source struct {
 	client client.Client
-	next   source.Contract
 }
 
-var _ source.Contract = &Source{}
+var (
+	_ source.Contract       = &Source{}
+	_ source.SampleProvider = &Source{}
+)
 
 // NewSource creates a new ConfigMap handling Source.
-func NewSource(client client.Client, next source.Contract) *Source {
-	return &Source{client: client, next: next}
+func NewSource(client client.Client) *Source {
+	return &Source{client: client}
 }
 
 // FetchCRD fetches the latest CRD if there is an update available.
 // The returned thing is the location to the CRD. This function should not return the CRD content
 // as it could be several megabytes large.
 func (s *Source) FetchCRD(ctx context.Context, dir string, obj *v1alpha1.Bootstrap, revision string) (string, error) {
-	if obj.Spec.Source.ConfigMap == nil {
-		if s.next == nil {
-			return "", errors.New("configmap isn't defined and there are no other sources configured")
-		}
-
-		return s.next.FetchCRD(ctx, dir, obj, revision)
-	}
-
 	configMap := &v1.ConfigMap{}
 
 	err := s.client.Get(ctx, types.NamespacedName{
@@ -79,6 +73,38 @@ func (s *Source) FetchCRD(ctx context.Context, dir string, obj *v1alpha1.Bootstr
 	return file, nil
 }
 
+// FetchSamples writes the content of the key path from the config map into a file. The key may contain
+// multiple YAML documents.
+func (s *Source) FetchSamples(ctx context.Context, dir string, obj *v1alpha1.Bootstrap, revision, path string) (string, error) {
+	configMap := &v1.ConfigMap{}
+
+	err := s.client.Get(ctx, types.NamespacedName{
+		Name:      obj.Spec.Source.ConfigMap.Name,
+		Namespace: obj.Spec.Source.ConfigMap.Namespace,
+	}, configMap)
+	if err != nil {
+		return "", fmt.Errorf("failed to find config map: %w", err)
+	}
+
+	if v := configMap.Data[version]; v != revision {
+		return "", fmt.Errorf("fetched revision '%s' does not equal requested '%s'", v, revision)
+	}
+
+	content, ok := configMap.Data[path]
+	if !ok {
+		return "", fmt.Errorf("failed to find '%s' in config map", path)
+	}
+
+	file := filepath.Join(dir, "samples.yaml")
+
+	const perm = 0o600
+	if err := os.WriteFile(file, []byte(content), perm); err != nil {
+		return "", fmt.Errorf("failed to create samples file from config map: %w", err)
+	}
+
+	return file, nil
+}
+
 // HasUpdate returns true and the version if there is an update available.
 // In case of a URL this would be the digest. This logic follows this general guide:
 // - Fetch latest version that satisfies the constraint
@@ -86,14 +112,6 @@ func (s *Source) FetchCRD(ctx context.Context, dir string, obj *v1alpha1.Bootstr
 // - Return true and the version if there is something to apply
 // - Return false and empty string if there is nothing to apply.
 func (s *Source) HasUpdate(ctx context.Context, obj *v1alpha1.Bootstrap) (bool, string, error) {
-	if obj.Spec.Source.ConfigMap == nil {
-		if s.next == nil {
-			return false, "", errors.New("configmap isn't defined and there are no other sources configured")
-		}
-
-		return s.next.HasUpdate(ctx, obj)
-	}
-
 	configMap := &v1.ConfigMap{}
 	if err := s.client.Get(ctx, types.NamespacedName{
 		Name:      obj.Spec.Source.ConfigMap.Name,
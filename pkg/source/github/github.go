@@ -7,9 +7,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
-	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
@@ -28,48 +28,58 @@ const (
 type Source struct {
 	Client *http.Client
 
-	client client.Client
-	next   source.Contract
+	client      client.Client
+	cache       *source.VersionCache
+	conditional *source.ConditionalCache
+	tokens      *auth.InstallationTokens
 }
 
-var _ source.Contract = &Source{}
+var (
+	_ source.Contract       = &Source{}
+	_ source.SampleProvider = &Source{}
+)
 
-// NewSource creates a new GitHub handling Source.
-func NewSource(c *http.Client, client client.Client, next source.Contract) *Source {
-	return &Source{Client: c, client: client, next: next}
+// NewSource creates a new GitHub handling Source. Latest versions are shared between Bootstraps through
+// the cache, unchanged releases are revalidated through the conditional cache, and installation tokens of
+// GitHub Apps are shared through tokens. All of them may be nil.
+func NewSource(c *http.Client, client client.Client, cache *source.VersionCache, conditional *source.ConditionalCache, tokens *auth.InstallationTokens) *Source {
+	return &Source{Client: c, client: client, cache: cache, conditional: conditional, tokens: tokens}
 }
 
 func (s *Source) FetchCRD(ctx context.Context, dir string, obj *v1alpha1.Bootstrap, revision string) (string, error) {
-	if obj.Spec.Source.GitHub == nil {
-		if s.next == nil {
-			return "", errors.New("github isn't defined and there are no other sources configured")
-		}
-
-		return s.next.FetchCRD(ctx, dir, obj, revision)
+	location, err := s.fetch(ctx, revision, dir, obj.Spec.Source.GitHub.Manifest, obj)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch CRD: %w", err)
 	}
 
-	err := s.fetch(ctx, revision, dir, obj)
+	return location, nil
+}
+
+// FetchSamples fetches the release asset with the name path from the release of the given revision.
+func (s *Source) FetchSamples(ctx context.Context, dir string, obj *v1alpha1.Bootstrap, revision, path string) (string, error) {
+	location, err := s.fetch(ctx, revision, dir, path, obj)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch CRD: %w", err)
+		return "", fmt.Errorf("failed to fetch samples: %w", err)
 	}
 
-	return filepath.Join(dir, obj.Spec.Source.GitHub.Manifest), nil
+	return location, nil
 }
 
 func (s *Source) HasUpdate(ctx context.Context, obj *v1alpha1.Bootstrap) (bool, string, error) {
-	if obj.Spec.Source.GitHub == nil {
-		if s.next == nil {
-			return false, "", errors.New("github isn't defined and there are no other sources configured")
+	versions, err := s.cache.Versions(ctx, s.cacheKey(obj), func(ctx context.Context) ([]string, error) {
+		latestVersion, err := s.getLatestVersion(ctx, obj)
+		if err != nil {
+			return nil, err
 		}
 
-		return s.next.HasUpdate(ctx, obj)
-	}
-
-	latestVersion, err := s.getLatestVersion(ctx, obj)
+		return []string{latestVersion}, nil
+	})
 	if err != nil {
 		return false, "", fmt.Errorf("failed to retrieve latest version for github: %w", err)
 	}
 
+	latestVersion := versions[0]
+
 	latestVersionSemver, err := semver.NewVersion(latestVersion)
 	if err != nil {
 		return false, "", fmt.Errorf("failed to parse current version '%s' as semver: %w", latestVersion, err)
@@ -103,30 +113,63 @@ func (s *Source) HasUpdate(ctx context.Context, obj *v1alpha1.Bootstrap) (bool,
 	return false, obj.Status.LastAppliedRevision, nil
 }
 
-// getLatestVersion calls the GitHub API and returns the latest released version.
-func (s *Source) getLatestVersion(ctx context.Context, obj *v1alpha1.Bootstrap) (_ string, err error) {
-	logger := log.FromContext(ctx)
-	c := s.Client
+// cacheKey returns the key the latest version of the repository is cached with.
+func (s *Source) cacheKey(obj *v1alpha1.Bootstrap) source.CacheKey {
+	src := obj.Spec.Source.GitHub
 
-	if obj.Spec.Source.GitHub.SecretRef != nil {
-		var err error
+	key := source.CacheKey{
+		Type:      source.GitHub,
+		Endpoint:  baseAPIURL(obj),
+		Name:      src.Owner + "/" + src.Repo,
+		Transport: source.TransportKey(obj),
+	}
+	if src.SecretRef != nil {
+		key.Credentials = obj.Namespace + "/" + src.SecretRef.Name
+	}
 
-		c, err = auth.ConstructAuthenticatedClient(ctx, s.client, obj.Spec.Source.GitHub.SecretRef.Name, obj.Namespace)
-		if err != nil {
-			return "", fmt.Errorf("failed to construct authenticated client: %w", err)
-		}
+	return key
+}
+
+// baseAPIURL returns the URL of the GitHub API of the source.
+func baseAPIURL(obj *v1alpha1.Bootstrap) string {
+	if obj.Spec.Source.GitHub.BaseAPIURL != "" {
+		return obj.Spec.Source.GitHub.BaseAPIURL
+	}
+
+	return githubAPIBase
+}
+
+// httpClient returns the client dedicated to the Bootstrap, authenticated with its token or as its GitHub App
+// installation if it has credentials.
+func (s *Source) httpClient(ctx context.Context, obj *v1alpha1.Bootstrap) (*http.Client, error) {
+	c, err := source.NewHTTPClient(ctx, s.client, s.Client, obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct HTTP client: %w", err)
 	}
 
-	const duration = 15 * time.Second
+	if obj.Spec.Source.GitHub.SecretRef == nil {
+		return c, nil
+	}
 
-	c.Timeout = duration
+	c, err = auth.ConstructAuthenticatedClient(ctx, s.client, c, obj.Spec.Source.GitHub.SecretRef.Name, obj.Namespace,
+		auth.WithGitHubApp(baseAPIURL(obj), s.tokens))
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct authenticated client: %w", err)
+	}
 
-	baseAPIURL := obj.Spec.Source.GitHub.BaseAPIURL
-	if baseAPIURL == "" {
-		baseAPIURL = githubAPIBase
+	return c, nil
+}
+
+// getLatestVersion calls the GitHub API and returns the latest released version.
+func (s *Source) getLatestVersion(ctx context.Context, obj *v1alpha1.Bootstrap) (_ string, err error) {
+	logger := log.FromContext(ctx)
+
+	c, err := s.httpClient(ctx, obj)
+	if err != nil {
+		return "", err
 	}
 
-	latestURL := fmt.Sprintf("%s/repos/%s/%s/releases/latest", baseAPIURL, obj.Spec.Source.GitHub.Owner, obj.Spec.Source.GitHub.Repo)
+	latestURL := fmt.Sprintf("%s/repos/%s/%s/releases/latest", baseAPIURL(obj), obj.Spec.Source.GitHub.Owner, obj.Spec.Source.GitHub.Repo)
 	logger.Info("checking for latest version under url", "url", latestURL)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latestURL, nil)
@@ -134,71 +177,76 @@ func (s *Source) getLatestVersion(ctx context.Context, obj *v1alpha1.Bootstrap)
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	res, err := c.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("GitHub API call failed: %w", err)
-	}
+	key := s.cacheKey(obj)
 
-	defer func() {
-		if berr := res.Body.Close(); berr != nil {
-			err = errors.Join(err, berr)
-		}
-	}()
+	// Conditional requests that GitHub answers with 304 Not Modified don't count against the rate limit.
+	tag, err := source.Conditional(s.conditional, c, req, key.Credentials, key.Transport, func(res *http.Response) (string, error) {
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			content, err := io.ReadAll(res.Body)
+			if err != nil {
+				logger.Error(errors.New("failed to read body for further information"), "failed to read body for further information")
+			}
 
-	if res.StatusCode < 200 || res.StatusCode > 299 {
-		content, err := io.ReadAll(res.Body)
-		if err != nil {
-			logger.Error(errors.New("failed to read body for further information"), "failed to read body for further information")
-		}
+			logger.Error(fmt.Errorf("unexpected status code from github (%d)", res.StatusCode), "unexpected status code from github with message", "message", string(content))
 
-		logger.Error(fmt.Errorf("unexpected status code from github (%d)", res.StatusCode), "unexpected status code from github with message", "message", string(content))
+			return "", fmt.Errorf("GitHub API returned an error: %w", source.NewStatusError(res))
+		}
 
-		return "", fmt.Errorf("GitHub API returned an unexpected status code (%d)", res.StatusCode)
-	}
+		type meta struct {
+			Tag string `json:"tag_name"`
+		}
 
-	type meta struct {
-		Tag string `json:"tag_name"`
-	}
+		var m meta
+		if err := json.NewDecoder(res.Body).Decode(&m); err != nil {
+			return "", fmt.Errorf("decoding GitHub API response failed: %w", err)
+		}
 
-	var m meta
-	if err := json.NewDecoder(res.Body).Decode(&m); err != nil {
-		return "", fmt.Errorf("decoding GitHub API response failed: %w", err)
+		return m.Tag, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("GitHub API call failed: %w", err)
 	}
 
-	if m.Tag == "" {
+	if tag == "" {
 		return "", errors.New("failed to retrieve latest version, please make sure owner and repo are spelled correctly")
 	}
 
-	return m.Tag, err
+	return tag, nil
 }
 
-// fetch fetches the content.
-func (s *Source) fetch(ctx context.Context, version, dir string, obj *v1alpha1.Bootstrap) (err error) {
-	baseURL := obj.Spec.Source.GitHub.BaseURL
-	if baseURL == "" {
-		baseURL = githubBase
+// fetch fetches the content of the given release asset and returns its location. Sources with credentials download
+// the asset through the API, because only the API accepts tokens reliably for private repositories. Other sources
+// use the download URL of the release page, which doesn't count against the API rate limit.
+func (s *Source) fetch(ctx context.Context, version, dir, asset string, obj *v1alpha1.Bootstrap) (_ string, err error) {
+	client, err := s.httpClient(ctx, obj)
+	if err != nil {
+		return "", err
 	}
 
-	baseURL = fmt.Sprintf("%s/%s/%s/releases", baseURL, obj.Spec.Source.GitHub.Owner, obj.Spec.Source.GitHub.Repo)
-	downloadURL := fmt.Sprintf("%s/download/%s/%s", baseURL, version, obj.Spec.Source.GitHub.Manifest)
+	authenticated := obj.Spec.Source.GitHub.SecretRef != nil
+
+	assetURL := downloadURL(obj, version, asset)
+	if authenticated {
+		assetURL, err = s.assetURL(ctx, client, version, asset, obj)
+		if err != nil {
+			return "", err
+		}
+	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, assetURL, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create HTTP request for %s, error: %w", downloadURL, err)
+		return "", fmt.Errorf("failed to create HTTP request for %s, error: %w", assetURL, err)
 	}
 
-	// download
-	client := s.Client
-	if obj.Spec.Source.GitHub.SecretRef != nil {
-		client, err = auth.ConstructAuthenticatedClient(ctx, s.client, obj.Spec.Source.GitHub.SecretRef.Name, obj.Namespace)
-		if err != nil {
-			return fmt.Errorf("failed to construct authenticated client: %w", err)
-		}
+	if authenticated {
+		// Without this, the API returns the metadata of the asset. With it, the API redirects to the storage of the
+		// content, which doesn't get the credentials, because the client only authenticates requests to the API host.
+		req.Header.Set("Accept", "application/octet-stream")
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to download %s from %s, error: %w", obj.Spec.Source.GitHub.Manifest, downloadURL, err)
+		return "", fmt.Errorf("failed to download %s from %s, error: %w", asset, assetURL, err)
 	}
 
 	defer func() {
@@ -209,12 +257,12 @@ func (s *Source) fetch(ctx context.Context, version, dir string, obj *v1alpha1.B
 
 	// check response
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download %s from %s, status: %s", obj.Spec.Source.GitHub.Manifest, downloadURL, resp.Status)
+		return "", fmt.Errorf("failed to download %s from %s: %w", asset, assetURL, source.NewStatusError(resp))
 	}
 
-	wf, err := os.Create(filepath.Clean(filepath.Join(dir, obj.Spec.Source.GitHub.Manifest)))
+	wf, err := os.Create(filepath.Clean(filepath.Join(dir, filepath.Base(asset))))
 	if err != nil {
-		return fmt.Errorf("failed to open temp file: %w", err)
+		return "", fmt.Errorf("failed to open temp file: %w", err)
 	}
 
 	defer func() {
@@ -224,8 +272,68 @@ func (s *Source) fetch(ctx context.Context, version, dir string, obj *v1alpha1.B
 	}()
 
 	if _, err := io.Copy(wf, resp.Body); err != nil {
-		return fmt.Errorf("failed to write to temp file: %w", err)
+		return "", fmt.Errorf("failed to write to temp file: %w", err)
+	}
+
+	return wf.Name(), nil
+}
+
+// downloadURL returns the URL of the asset with the given name on the release page of version.
+func downloadURL(obj *v1alpha1.Bootstrap, version, asset string) string {
+	src := obj.Spec.Source.GitHub
+
+	baseURL := src.BaseURL
+	if baseURL == "" {
+		baseURL = githubBase
+	}
+
+	return fmt.Sprintf("%s/%s/%s/releases/download/%s/%s", baseURL, src.Owner, src.Repo, version, asset)
+}
+
+// assetURL returns the API URL of the asset with the given name in the release of version.
+func (s *Source) assetURL(ctx context.Context, client *http.Client, version, asset string, obj *v1alpha1.Bootstrap) (_ string, err error) {
+	src := obj.Spec.Source.GitHub
+	releaseURL := fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", baseAPIURL(obj), src.Owner, src.Repo, url.PathEscape(version))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releaseURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch release %s, error: %w", version, err)
+	}
+
+	defer func() {
+		if berr := resp.Body.Close(); berr != nil {
+			err = errors.Join(err, berr)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch release %s from %s: %w", version, releaseURL, source.NewStatusError(resp))
+	}
+
+	type release struct {
+		Assets []struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		} `json:"assets"`
+	}
+
+	var r release
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return "", fmt.Errorf("decoding GitHub API response failed: %w", err)
+	}
+
+	for _, a := range r.Assets {
+		if a.Name == asset {
+			return fmt.Sprintf("%s/repos/%s/%s/releases/assets/%d", baseAPIURL(obj), src.Owner, src.Repo, a.ID), nil
+		}
 	}
 
-	return nil
+	return "", fmt.Errorf("asset %s not found in release %s: %w", asset, version, source.ErrNotFound)
 }
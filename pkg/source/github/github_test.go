@@ -0,0 +1,256 @@
+package github
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+	"github.com/Skarlso/crd-bootstrap/pkg/source"
+	"github.com/Skarlso/crd-bootstrap/pkg/source/auth"
+)
+
+// fakeGitHub serves release v1.0.0 of owner/repo with the asset crds.yaml, whose content is stored on another host
+// like on github.com. Requests to the API without the token are rejected, the release page is public.
+type fakeGitHub struct {
+	web     *httptest.Server
+	api     *httptest.Server
+	storage *httptest.Server
+
+	apiRequests  atomic.Int32
+	mints        atomic.Int32
+	unauthorized atomic.Int32
+	// storageAuthorization is the Authorization header the storage received.
+	storageAuthorization atomic.Value
+}
+
+func newFakeGitHub(t *testing.T, token string) *fakeGitHub {
+	t.Helper()
+
+	f := &fakeGitHub{}
+
+	f.storage = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.storageAuthorization.Store(r.Header.Get("Authorization"))
+
+		_, _ = w.Write([]byte("kind: CustomResourceDefinition\n"))
+	}))
+	t.Cleanup(f.storage.Close)
+
+	f.web = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/owner/repo/releases/download/v1.0.0/crds.yaml" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		http.Redirect(w, r, f.storage.URL+"/crds.yaml?signature=abc", http.StatusFound)
+	}))
+	t.Cleanup(f.web.Close)
+
+	f.api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.apiRequests.Add(1)
+
+		if r.URL.Path == "/app/installations/42/access_tokens" {
+			f.mints.Add(1)
+
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"token":"` + token + `","expires_at":"` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`))
+
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			f.unauthorized.Add(1)
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		switch r.URL.Path {
+		case "/repos/owner/repo/releases/latest":
+			_, _ = w.Write([]byte(`{"tag_name":"v1.0.0"}`))
+		case "/repos/owner/repo/releases/tags/v1.0.0":
+			_, _ = w.Write([]byte(`{"tag_name":"v1.0.0","assets":[{"id":1,"name":"samples.yaml"},{"id":7,"name":"crds.yaml"}]}`))
+		case "/repos/owner/repo/releases/assets/7":
+			if r.Header.Get("Accept") != "application/octet-stream" {
+				_, _ = w.Write([]byte(`{"id":7,"name":"crds.yaml"}`))
+
+				return
+			}
+
+			http.Redirect(w, r, f.storage.URL+"/crds.yaml?signature=abc", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(f.api.Close)
+
+	return f
+}
+
+func bootstrap(baseAPIURL, secret string) *v1alpha1.Bootstrap {
+	return &v1alpha1.Bootstrap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: v1alpha1.BootstrapSpec{
+			Source: &v1alpha1.Source{GitHub: &v1alpha1.GitHub{
+				BaseAPIURL: baseAPIURL,
+				Owner:      "owner",
+				Repo:       "repo",
+				Manifest:   "crds.yaml",
+				SecretRef:  &corev1.LocalObjectReference{Name: secret},
+			}},
+			Version: v1alpha1.Version{Semver: ">=1.0.0"},
+		},
+	}
+}
+
+func TestFetchCRDDownloadsAssetThroughAPI(t *testing.T) {
+	tests := []struct {
+		name        string
+		revision    string
+		manifest    string
+		expectedErr error
+	}{
+		{
+			name:     "asset is downloaded from the storage the API redirects to",
+			revision: "v1.0.0",
+			manifest: "crds.yaml",
+		},
+		{
+			name:        "asset isn't part of the release",
+			revision:    "v1.0.0",
+			manifest:    "missing.yaml",
+			expectedErr: source.ErrNotFound,
+		},
+		{
+			name:        "release doesn't exist",
+			revision:    "v2.0.0",
+			manifest:    "crds.yaml",
+			expectedErr: source.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeGitHub(t, "ghp_token")
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "default"},
+				Data:       map[string][]byte{v1alpha1.TokenKey: []byte("ghp_token")},
+			}
+
+			s := NewSource(f.api.Client(), fake.NewClientBuilder().WithObjects(secret).Build(), nil, nil, nil)
+			obj := bootstrap(f.api.URL, "token")
+			obj.Spec.Source.GitHub.Manifest = tt.manifest
+
+			location, err := s.FetchCRD(t.Context(), t.TempDir(), obj, tt.revision)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(location)
+			require.NoError(t, err)
+			assert.Equal(t, "kind: CustomResourceDefinition\n", string(content))
+
+			assert.Zero(t, f.unauthorized.Load())
+			assert.Empty(t, f.storageAuthorization.Load(), "the token must not be sent to the storage")
+		})
+	}
+}
+
+func TestFetchCRDDownloadsPublicAssetFromReleasePage(t *testing.T) {
+	tests := []struct {
+		name        string
+		manifest    string
+		expectedErr error
+	}{
+		{
+			name:     "asset is downloaded from the release page",
+			manifest: "crds.yaml",
+		},
+		{
+			name:        "asset isn't part of the release",
+			manifest:    "missing.yaml",
+			expectedErr: source.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeGitHub(t, "ghp_token")
+
+			s := NewSource(f.api.Client(), fake.NewClientBuilder().Build(), nil, nil, nil)
+			obj := bootstrap(f.api.URL, "")
+			obj.Spec.Source.GitHub.BaseURL = f.web.URL
+			obj.Spec.Source.GitHub.SecretRef = nil
+			obj.Spec.Source.GitHub.Manifest = tt.manifest
+
+			location, err := s.FetchCRD(t.Context(), t.TempDir(), obj, "v1.0.0")
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(location)
+			require.NoError(t, err)
+			assert.Equal(t, "kind: CustomResourceDefinition\n", string(content))
+
+			assert.Zero(t, f.apiRequests.Load(), "public assets must not count against the API rate limit")
+		})
+	}
+}
+
+func TestGitHubAppAuthentication(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	f := newFakeGitHub(t, "ghs_installation")
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Data: map[string][]byte{
+			v1alpha1.AppIDKey:          []byte("1234"),
+			v1alpha1.InstallationIDKey: []byte("42"),
+			v1alpha1.PrivateKeyKey:     pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		},
+	}
+
+	s := NewSource(f.api.Client(), fake.NewClientBuilder().WithObjects(secret).Build(), nil, nil, auth.NewInstallationTokens())
+	obj := bootstrap(f.api.URL, "app")
+
+	update, revision, err := s.HasUpdate(t.Context(), obj)
+	require.NoError(t, err)
+	assert.True(t, update)
+	assert.Equal(t, "v1.0.0", revision)
+
+	location, err := s.FetchCRD(t.Context(), t.TempDir(), obj, revision)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(location)
+	require.NoError(t, err)
+	assert.Equal(t, "kind: CustomResourceDefinition\n", string(content))
+
+	assert.Equal(t, int32(1), f.mints.Load())
+	assert.Zero(t, f.unauthorized.Load())
+	assert.Empty(t, f.storageAuthorization.Load())
+}
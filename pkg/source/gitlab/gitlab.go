@@ -9,7 +9,6 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/Skarlso/crd-bootstrap/pkg/source/auth"
@@ -28,48 +27,56 @@ const (
 type Source struct {
 	Client *http.Client
 
-	client client.Client
-	next   source.Contract
+	client      client.Client
+	cache       *source.VersionCache
+	conditional *source.ConditionalCache
 }
 
-var _ source.Contract = &Source{}
+var (
+	_ source.Contract       = &Source{}
+	_ source.SampleProvider = &Source{}
+)
 
-// NewSource creates a new gitlab handling Source.
-func NewSource(c *http.Client, client client.Client, next source.Contract) *Source {
-	return &Source{Client: c, client: client, next: next}
+// NewSource creates a new gitlab handling Source. Latest versions are shared between Bootstraps through
+// the cache, and unchanged releases are revalidated through the conditional cache. Both may be nil.
+func NewSource(c *http.Client, client client.Client, cache *source.VersionCache, conditional *source.ConditionalCache) *Source {
+	return &Source{Client: c, client: client, cache: cache, conditional: conditional}
 }
 
 func (s *Source) FetchCRD(ctx context.Context, dir string, obj *v1alpha1.Bootstrap, revision string) (string, error) {
-	if obj.Spec.Source.GitLab == nil {
-		if s.next == nil {
-			return "", errors.New("gitlab isn't defined and there are no other sources configured")
-		}
-
-		return s.next.FetchCRD(ctx, dir, obj, revision)
+	location, err := s.fetch(ctx, revision, dir, obj.Spec.Source.GitLab.Manifest, obj)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch CRD: %w", err)
 	}
 
-	err := s.fetch(ctx, revision, dir, obj)
+	return location, nil
+}
+
+// FetchSamples fetches the release asset link with the name path from the release of the given revision.
+func (s *Source) FetchSamples(ctx context.Context, dir string, obj *v1alpha1.Bootstrap, revision, path string) (string, error) {
+	location, err := s.fetch(ctx, revision, dir, path, obj)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch CRD: %w", err)
+		return "", fmt.Errorf("failed to fetch samples: %w", err)
 	}
 
-	return filepath.Join(dir, obj.Spec.Source.GitLab.Manifest), nil
+	return location, nil
 }
 
 func (s *Source) HasUpdate(ctx context.Context, obj *v1alpha1.Bootstrap) (bool, string, error) {
-	if obj.Spec.Source.GitLab == nil {
-		if s.next == nil {
-			return false, "", errors.New("gitlab isn't defined and there are no other sources configured")
+	versions, err := s.cache.Versions(ctx, s.cacheKey(obj), func(ctx context.Context) ([]string, error) {
+		latestVersion, err := s.getLatestVersion(ctx, obj)
+		if err != nil {
+			return nil, err
 		}
 
-		return s.next.HasUpdate(ctx, obj)
-	}
-
-	latestVersion, err := s.getLatestVersion(ctx, obj)
+		return []string{latestVersion}, nil
+	})
 	if err != nil {
 		return false, "", fmt.Errorf("failed to retrieve latest version for gitlab: %w", err)
 	}
 
+	latestVersion := versions[0]
+
 	latestVersionSemver, err := semver.NewVersion(latestVersion)
 	if err != nil {
 		return false, "", fmt.Errorf("failed to parse current version '%s' as semver: %w", latestVersion, err)
@@ -103,23 +110,55 @@ func (s *Source) HasUpdate(ctx context.Context, obj *v1alpha1.Bootstrap) (bool,
 	return false, obj.Status.LastAppliedRevision, nil
 }
 
-// getLatestVersion calls the gitlab API and returns the latest released version.
-func (s *Source) getLatestVersion(ctx context.Context, obj *v1alpha1.Bootstrap) (_ string, err error) {
-	logger := log.FromContext(ctx)
-	c := s.Client
+// cacheKey returns the key the latest version of the repository is cached with.
+func (s *Source) cacheKey(obj *v1alpha1.Bootstrap) source.CacheKey {
+	src := obj.Spec.Source.GitLab
 
-	if obj.Spec.Source.GitLab.SecretRef != nil {
-		var err error
+	baseAPIURL := src.BaseAPIURL
+	if baseAPIURL == "" {
+		baseAPIURL = gitlabAPIBase
+	}
 
-		c, err = auth.ConstructAuthenticatedClient(ctx, s.client, obj.Spec.Source.GitLab.SecretRef.Name, obj.Namespace)
-		if err != nil {
-			return "", fmt.Errorf("failed to construct authenticated client: %w", err)
-		}
+	key := source.CacheKey{
+		Type:      source.GitLab,
+		Endpoint:  baseAPIURL,
+		Name:      src.Owner + "/" + src.Repo,
+		Transport: source.TransportKey(obj),
+	}
+	if src.SecretRef != nil {
+		key.Credentials = obj.Namespace + "/" + src.SecretRef.Name
 	}
 
-	const duration = 15 * time.Second
+	return key
+}
+
+// httpClient returns the client dedicated to the Bootstrap, authenticated with its token if it has one.
+func (s *Source) httpClient(ctx context.Context, obj *v1alpha1.Bootstrap) (*http.Client, error) {
+	c, err := source.NewHTTPClient(ctx, s.client, s.Client, obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct HTTP client: %w", err)
+	}
 
-	c.Timeout = duration
+	if obj.Spec.Source.GitLab.SecretRef == nil {
+		return c, nil
+	}
+
+	c, err = auth.ConstructAuthenticatedClient(ctx, s.client, c, obj.Spec.Source.GitLab.SecretRef.Name, obj.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct authenticated client: %w", err)
+	}
+
+	return c, nil
+}
+
+// getLatestVersion calls the gitlab API and returns the latest released version.
+func (s *Source) getLatestVersion(ctx context.Context, obj *v1alpha1.Bootstrap) (_ string, err error) {
+	logger := log.FromContext(ctx)
+
+	c, err := s.httpClient(ctx, obj)
+	if err != nil {
+		return "", err
+	}
 
 	baseAPIURL := obj.Spec.Source.GitLab.BaseAPIURL
 	if baseAPIURL == "" {
@@ -131,70 +170,70 @@ func (s *Source) getLatestVersion(ctx context.Context, obj *v1alpha1.Bootstrap)
 	latestURL := fmt.Sprintf("%s/projects/%s%s%s/releases/permalink/latest", baseAPIURL, obj.Spec.Source.GitLab.Owner, "%2F", obj.Spec.Source.GitLab.Repo)
 	logger.Info("checking for latest version under url", "url", latestURL)
 
-	body, err := s.fetchURLContent(ctx, c, latestURL)
-	// immediately check even in case of error.
-	defer func() {
-		if cerr := body.Close(); cerr != nil {
-			err = errors.Join(err, cerr)
-		}
-	}()
-
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latestURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to read url content: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	type meta struct {
-		Tag string `json:"tag_name"`
-	}
+	key := s.cacheKey(obj)
+
+	tag, err := source.Conditional(s.conditional, c, req, key.Credentials, key.Transport, func(res *http.Response) (string, error) {
+		if err := checkResponse(ctx, res); err != nil {
+			return "", err
+		}
+
+		type meta struct {
+			Tag string `json:"tag_name"`
+		}
+
+		var m meta
+		if err := json.NewDecoder(res.Body).Decode(&m); err != nil {
+			return "", fmt.Errorf("decoding gitlab API response failed: %w", err)
+		}
 
-	var m meta
-	if err := json.NewDecoder(body).Decode(&m); err != nil {
-		return "", fmt.Errorf("decoding gitlab API response failed: %w", err)
+		return m.Tag, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read url content: %w", err)
 	}
 
-	if m.Tag == "" {
+	if tag == "" {
 		return "", errors.New("failed to retrieve latest version, please make sure owner and repo are spelled correctly")
 	}
 
-	logger.Info("latest version found", "version", m.Tag)
+	logger.Info("latest version found", "version", tag)
 
-	return m.Tag, err
+	return tag, nil
 }
 
-// fetch fetches the content.
-func (s *Source) fetch(ctx context.Context, version, dir string, obj *v1alpha1.Bootstrap) error {
+// fetch fetches the content of the given release asset link and returns its location.
+func (s *Source) fetch(ctx context.Context, version, dir, asset string, obj *v1alpha1.Bootstrap) (_ string, err error) {
 	baseAPIURL := obj.Spec.Source.GitLab.BaseAPIURL
 	if baseAPIURL == "" {
 		baseAPIURL = gitlabAPIBase
 	}
 
 	// construct client
-	var err error
-
-	client := s.Client
-	if obj.Spec.Source.GitLab.SecretRef != nil {
-		client, err = auth.ConstructAuthenticatedClient(ctx, s.client, obj.Spec.Source.GitLab.SecretRef.Name, obj.Namespace)
-		if err != nil {
-			return fmt.Errorf("failed to construct authenticated client: %w", err)
-		}
+	client, err := s.httpClient(ctx, obj)
+	if err != nil {
+		return "", err
 	}
 
 	downloadURL := fmt.Sprintf("%s/projects/%s%s%s/releases/%s", baseAPIURL, obj.Spec.Source.GitLab.Owner, "%2F", obj.Spec.Source.GitLab.Repo, version)
 	body, err := s.fetchURLContent(ctx, client, downloadURL)
-	// immediately check even in case of error.
+	if err != nil {
+		return "", fmt.Errorf("failed to download url content: %w", err)
+	}
+
 	defer func() {
 		if cerr := body.Close(); cerr != nil {
 			err = errors.Join(err, cerr)
 		}
 	}()
 
-	if err != nil {
-		return fmt.Errorf("failed to download url content: %w", err)
-	}
-
 	content, err := io.ReadAll(body)
 	if err != nil {
-		return fmt.Errorf("failed to read full body: %w", err)
+		return "", fmt.Errorf("failed to read full body: %w", err)
 	}
 
 	type meta struct {
@@ -208,13 +247,13 @@ func (s *Source) fetch(ctx context.Context, version, dir string, obj *v1alpha1.B
 
 	var assets meta
 	if err := json.Unmarshal(content, &assets); err != nil {
-		return fmt.Errorf("failed to marshal response: %w", err)
+		return "", fmt.Errorf("failed to marshal response: %w", err)
 	}
 
 	var assetURL string
 
 	for _, a := range assets.Assets.Links {
-		if a.Name == obj.Spec.Source.GitLab.Manifest {
+		if a.Name == asset {
 			assetURL = a.URL
 
 			break
@@ -222,24 +261,23 @@ func (s *Source) fetch(ctx context.Context, version, dir string, obj *v1alpha1.B
 	}
 
 	if assetURL == "" {
-		return fmt.Errorf("asset link not found under release assets in release with name %s", obj.Spec.Source.GitLab.Manifest)
+		return "", fmt.Errorf("asset link not found under release assets in release with name %s", asset)
 	}
 
 	assetBody, err := s.fetchURLContent(ctx, client, assetURL)
-	// immediately check even in case of error.
+	if err != nil {
+		return "", fmt.Errorf("failed to download url content: %w", err)
+	}
+
 	defer func() {
 		if cerr := assetBody.Close(); cerr != nil {
 			err = errors.Join(err, cerr)
 		}
 	}()
 
+	wf, err := os.Create(filepath.Clean(filepath.Join(dir, filepath.Base(asset))))
 	if err != nil {
-		return fmt.Errorf("failed to download url content: %w", err)
-	}
-
-	wf, err := os.Create(filepath.Clean(filepath.Join(dir, obj.Spec.Source.GitLab.Manifest)))
-	if err != nil {
-		return fmt.Errorf("failed to open temp file: %w", err)
+		return "", fmt.Errorf("failed to open temp file: %w", err)
 	}
 
 	defer func() {
@@ -250,16 +288,14 @@ func (s *Source) fetch(ctx context.Context, version, dir string, obj *v1alpha1.B
 
 	// stream the asset content into a temp file
 	if _, err := io.Copy(wf, assetBody); err != nil {
-		return fmt.Errorf("failed to write to temp file: %w", err)
+		return "", fmt.Errorf("failed to write to temp file: %w", err)
 	}
 
-	return nil
+	return wf.Name(), nil
 }
 
 // fetchURLContent return the body as a reader so the caller can stream it.
 func (s *Source) fetchURLContent(ctx context.Context, c *http.Client, url string) (io.ReadCloser, error) {
-	logger := log.FromContext(ctx)
-
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -270,6 +306,17 @@ func (s *Source) fetchURLContent(ctx context.Context, c *http.Client, url string
 		return nil, fmt.Errorf("gitlab API call failed: %w", err)
 	}
 
+	if err := checkResponse(ctx, res); err != nil {
+		return nil, errors.Join(err, res.Body.Close())
+	}
+
+	return res.Body, nil
+}
+
+// checkResponse returns an error if the response doesn't have a successful status code.
+func checkResponse(ctx context.Context, res *http.Response) error {
+	logger := log.FromContext(ctx)
+
 	if res.StatusCode < 200 || res.StatusCode > 299 {
 		content, err := io.ReadAll(res.Body)
 		if err != nil {
@@ -278,8 +325,8 @@ func (s *Source) fetchURLContent(ctx context.Context, c *http.Client, url string
 
 		logger.Error(fmt.Errorf("unexpected status code from gitlab (%d)", res.StatusCode), "unexpected status code from gitlab with message", "message", string(content))
 
-		return nil, fmt.Errorf("gitlab API returned an unexpected status code (%d)", res.StatusCode)
+		return fmt.Errorf("gitlab API returned an error: %w", source.NewStatusError(res))
 	}
 
-	return res.Body, nil
+	return nil
 }
@@ -35,30 +35,107 @@ import (
 type Source struct {
 	Client *http.Client
 
-	client client.Client
-	next   source.Contract
+	client      client.Client
+	cache       *source.VersionCache
+	conditional *source.ConditionalCache
 }
 
-var _ source.Contract = &Source{}
+var (
+	_ source.Contract       = &Source{}
+	_ source.SampleProvider = &Source{}
+)
 
-// NewSource creates a new Helm handling Source.
-func NewSource(c *http.Client, client client.Client, next source.Contract) *Source {
-	return &Source{Client: c, client: client, next: next}
+// NewSource creates a new Helm handling Source. Chart versions are shared between Bootstraps through the cache,
+// and unchanged repository indexes are revalidated through the conditional cache. Both may be nil.
+func NewSource(c *http.Client, client client.Client, cache *source.VersionCache, conditional *source.ConditionalCache) *Source {
+	return &Source{Client: c, client: client, cache: cache, conditional: conditional}
 }
 
 func (s *Source) FetchCRD(ctx context.Context, dir string, obj *v1alpha1.Bootstrap, revision string) (_ string, err error) {
-	if obj.Spec.Source.Helm == nil {
-		if s.next == nil {
-			return "", errors.New("helm isn't defined and there are no other sources configured")
+	tempHelm := filepath.Join(dir, "helm-temp")
+
+	defer func() {
+		if rerr := os.RemoveAll(tempHelm); rerr != nil {
+			err = errors.Join(err, rerr)
 		}
+	}()
 
-		return s.next.FetchCRD(ctx, dir, obj, revision)
+	if _, err := s.downloadChart(ctx, tempHelm, obj, revision); err != nil {
+		return "", err
 	}
 
-	var (
-		out     strings.Builder
-		options []getter.Option
-	)
+	if err := s.createCrdYaml(dir, tempHelm); err != nil {
+		return "", fmt.Errorf("failed to create crd yaml: %w", err)
+	}
+
+	return filepath.Join(dir, "crds.yaml"), nil
+}
+
+// FetchSamples fetches all files under the given path of the chart. The path is relative to the root of the chart.
+func (s *Source) FetchSamples(ctx context.Context, dir string, obj *v1alpha1.Bootstrap, revision, path string) (_ string, err error) {
+	tempHelm := filepath.Join(dir, "helm-samples-temp")
+
+	defer func() {
+		if rerr := os.RemoveAll(tempHelm); rerr != nil {
+			err = errors.Join(err, rerr)
+		}
+	}()
+
+	outputPath, err := s.downloadChart(ctx, tempHelm, obj, revision)
+	if err != nil {
+		return "", err
+	}
+
+	// OCI charts are already expanded during download.
+	if !registry.IsOCI(obj.Spec.Source.Helm.ChartReference) {
+		if err := chartutil.ExpandFile(tempHelm, outputPath); err != nil {
+			return "", fmt.Errorf("failed to untar: %w", err)
+		}
+	}
+
+	root := filepath.Join(tempHelm, obj.Spec.Source.Helm.ChartName, filepath.Clean(string(filepath.Separator)+path))
+	if _, err := os.Stat(root); err != nil {
+		return "", fmt.Errorf("failed to find samples path %s in chart: %w", path, err)
+	}
+
+	samples, err := os.Create(filepath.Clean(filepath.Join(dir, "samples.yaml")))
+	if err != nil {
+		return "", fmt.Errorf("failed to create samples file: %w", err)
+	}
+
+	defer func() {
+		if cerr := samples.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+	}()
+
+	if err := s.appendFilesToCrds(root, samples); err != nil {
+		return "", fmt.Errorf("failed to collect samples: %w", err)
+	}
+
+	return samples.Name(), nil
+}
+
+// downloadChart downloads the chart with the given version into dir and returns the location of the chart archive.
+// OCI charts are also expanded.
+func (s *Source) downloadChart(ctx context.Context, dir string, obj *v1alpha1.Bootstrap, revision string) (string, error) {
+	var out strings.Builder
+
+	transport, err := source.NewHTTPTransport(ctx, s.client, obj.Namespace, obj.Spec.Source.Helm.Transport)
+	if err != nil {
+		return "", fmt.Errorf("failed to construct HTTP transport: %w", err)
+	}
+
+	timeout := source.Timeout(obj.Spec.Source.Helm.Transport)
+	options := []getter.Option{getter.WithTimeout(timeout)}
+
+	// The getters build their own clients, they only take the transport with the CA bundle, client certificate and
+	// proxy of the source.
+	var registryHTTPClient *http.Client
+	if transport != nil {
+		options = append(options, getter.WithTransport(transport))
+		registryHTTPClient = &http.Client{Transport: transport, Timeout: timeout}
+	}
 
 	download := &downloader.ChartDownloader{
 		Out:     &out,
@@ -68,46 +145,34 @@ func (s *Source) FetchCRD(ctx context.Context, dir string, obj *v1alpha1.Bootstr
 	}
 
 	if obj.Spec.Source.Helm.SecretRef != nil {
-		err := s.configureCredentials(ctx, obj, download)
+		err := s.configureCredentials(ctx, obj, download, registryHTTPClient)
 		if err != nil {
 			return "", err
 		}
 	}
 
-	tempHelm := filepath.Join(dir, "helm-temp")
-
 	const perm = 0o755
 
-	if err := os.MkdirAll(tempHelm, perm); err != nil {
+	if err := os.MkdirAll(dir, perm); err != nil {
 		return "", fmt.Errorf("failed to create temp helm folder: %w", err)
 	}
 
-	defer func() {
-		if rerr := os.RemoveAll(tempHelm); rerr != nil {
-			err = errors.Join(err, rerr)
-		}
-	}()
-
-	outputPath, _, err := download.DownloadTo(obj.Spec.Source.Helm.ChartReference, revision, tempHelm)
+	outputPath, _, err := download.DownloadTo(obj.Spec.Source.Helm.ChartReference, revision, dir)
 	if err != nil {
 		return "", fmt.Errorf("failed to download chart: %w", err)
 	}
 
 	if registry.IsOCI(obj.Spec.Source.Helm.ChartReference) {
-		err := chartutil.ExpandFile(tempHelm, outputPath)
+		err := chartutil.ExpandFile(dir, outputPath)
 		if err != nil {
 			return "", fmt.Errorf("failed ot untar: %w", err)
 		}
 	}
 
-	if err := s.createCrdYaml(dir, tempHelm); err != nil {
-		return "", fmt.Errorf("failed to create crd yaml: %w", err)
-	}
-
-	return filepath.Join(dir, "crds.yaml"), nil
+	return outputPath, nil
 }
 
-func (s *Source) configureCredentials(ctx context.Context, obj *v1alpha1.Bootstrap, download *downloader.ChartDownloader) error {
+func (s *Source) configureCredentials(ctx context.Context, obj *v1alpha1.Bootstrap, download *downloader.ChartDownloader, httpClient *http.Client) error {
 	secret := &v1.Secret{}
 
 	err := s.client.Get(ctx, types.NamespacedName{Name: obj.Spec.Source.Helm.SecretRef.Name, Namespace: obj.Namespace}, secret)
@@ -116,7 +181,7 @@ func (s *Source) configureCredentials(ctx context.Context, obj *v1alpha1.Bootstr
 	}
 
 	if registry.IsOCI(obj.Spec.Source.Helm.ChartReference) {
-		err := s.configureOCICredentials(secret, obj.Spec.Source.Helm.ChartReference, download)
+		err := s.configureOCICredentials(secret, obj.Spec.Source.Helm.ChartReference, download, httpClient)
 		if err != nil {
 			return fmt.Errorf("failed to configure oci repository: %w", err)
 		}
@@ -185,28 +250,20 @@ type results struct {
 }
 
 func (s *Source) HasUpdate(ctx context.Context, obj *v1alpha1.Bootstrap) (bool, string, error) {
-	if obj.Spec.Source.Helm == nil {
-		if s.next == nil {
-			return false, "", errors.New("helm isn't defined and there are no other sources configured")
-		}
-
-		return s.next.HasUpdate(ctx, obj)
-	}
-
-	var (
-		versions []string
-		err      error
-	)
-	if registry.IsOCI(obj.Spec.Source.Helm.ChartReference) {
-		versions, err = s.findVersionsForOCIRegistry(ctx, obj.Spec.Source.Helm, obj.Namespace)
+	versions, err := s.cache.Versions(ctx, cacheKey(obj), func(ctx context.Context) ([]string, error) {
+		c, err := source.NewHTTPClient(ctx, s.client, s.Client, obj)
 		if err != nil {
-			return false, "", err
+			return nil, fmt.Errorf("failed to construct HTTP client: %w", err)
 		}
-	} else {
-		versions, err = s.findVersionsForHTTPRepository(ctx, obj.Spec.Source.Helm, obj.Namespace)
-		if err != nil {
-			return false, "", err
+
+		if registry.IsOCI(obj.Spec.Source.Helm.ChartReference) {
+			return s.findVersionsForOCIRegistry(ctx, c, obj.Spec.Source.Helm, obj.Namespace)
 		}
+
+		return s.findVersionsForHTTPRepository(ctx, c, obj.Spec.Source.Helm, obj.Namespace, source.TransportKey(obj))
+	})
+	if err != nil {
+		return false, "", err
 	}
 
 	// get latest version that applies to the constraint.
@@ -250,6 +307,23 @@ func (s *Source) HasUpdate(ctx context.Context, obj *v1alpha1.Bootstrap) (bool,
 	return false, obj.Status.LastAppliedRevision, nil
 }
 
+// cacheKey returns the key the versions of the chart are cached with.
+func cacheKey(obj *v1alpha1.Bootstrap) source.CacheKey {
+	chart := obj.Spec.Source.Helm
+
+	key := source.CacheKey{
+		Type:      source.Helm,
+		Endpoint:  chart.ChartReference,
+		Name:      chart.ChartName,
+		Transport: source.TransportKey(obj),
+	}
+	if chart.SecretRef != nil {
+		key.Credentials = obj.Namespace + "/" + chart.SecretRef.Name
+	}
+
+	return key
+}
+
 // getLatestVersion selects all the versions that match the constraint and gets back the latest.
 func (s *Source) getLatestVersion(versions []string, constraint *semver.Constraints) string {
 	semvers := make([]*semver.Version, 0)
@@ -272,7 +346,7 @@ func (s *Source) getLatestVersion(versions []string, constraint *semver.Constrai
 	return semvers[0].Original()
 }
 
-func (s *Source) findVersionsForOCIRegistry(ctx context.Context, chartRef *v1alpha1.Helm, namespace string) ([]string, error) {
+func (s *Source) findVersionsForOCIRegistry(ctx context.Context, c *http.Client, chartRef *v1alpha1.Helm, namespace string) ([]string, error) {
 	var versions []string
 	// helm's own way of doing this just doesn't work.
 	src, err := remote.NewRepository(strings.TrimPrefix(chartRef.ChartReference, "oci://"))
@@ -280,8 +354,10 @@ func (s *Source) findVersionsForOCIRegistry(ctx context.Context, chartRef *v1alp
 		return nil, fmt.Errorf("failed to construct repository: %w", err)
 	}
 
+	src.Client = &auth.Client{Client: c, Cache: auth.NewCache()}
+
 	if chartRef.SecretRef != nil {
-		err := s.configureTransportForOCIRepo(ctx, src, chartRef.SecretRef, namespace)
+		err := s.configureTransportForOCIRepo(ctx, c, src, chartRef.SecretRef, namespace)
 		if err != nil {
 			return nil, fmt.Errorf("failed to configure transport client: %w", err)
 		}
@@ -298,7 +374,7 @@ func (s *Source) findVersionsForOCIRegistry(ctx context.Context, chartRef *v1alp
 	return versions, nil
 }
 
-func (s *Source) findVersionsForHTTPRepository(ctx context.Context, chartRef *v1alpha1.Helm, namespace string) (_ []string, err error) {
+func (s *Source) findVersionsForHTTPRepository(ctx context.Context, c *http.Client, chartRef *v1alpha1.Helm, namespace, transport string) (_ []string, err error) {
 	u, err := url.JoinPath(chartRef.ChartReference, "index.yaml")
 	if err != nil {
 		return nil, fmt.Errorf("failed to join path: %w", err)
@@ -309,7 +385,7 @@ func (s *Source) findVersionsForHTTPRepository(ctx context.Context, chartRef *v1
 		return nil, fmt.Errorf("failed to construct request: %w", err)
 	}
 
-	innerClient := s.Client
+	innerClient := c
 
 	if chartRef.SecretRef != nil {
 		secret := &v1.Secret{}
@@ -319,68 +395,64 @@ func (s *Source) findVersionsForHTTPRepository(ctx context.Context, chartRef *v1
 			return nil, fmt.Errorf("failed to find attached secret: %w", err)
 		}
 
-		innerClient, err = s.configureHTTPCredentials(ctx, secret)
+		innerClient, err = s.configureHTTPCredentials(ctx, c, secret)
 		if err != nil {
 			return nil, fmt.Errorf("failed to configure secure access to HTTP repo: %w", err)
 		}
 	}
 
-	resp, err := innerClient.Do(req)
-	if err != nil {
-		return nil, err
+	var credentials string
+	if chartRef.SecretRef != nil {
+		credentials = namespace + "/" + chartRef.SecretRef.Name
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return nil, fmt.Errorf("status code returned is invalid %d", resp.StatusCode)
-	}
+	return source.Conditional(s.conditional, innerClient, req, credentials, transport, func(resp *http.Response) (_ []string, err error) {
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("failed to fetch repository index: %w", source.NewStatusError(resp))
+		}
 
-	defer func() {
-		if cerr := resp.Body.Close(); cerr != nil {
-			err = errors.Join(err, cerr)
+		// leaving dir empty will create a temp dir
+		tempFile, err := os.CreateTemp("", "index.yaml")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp file for response: %w", err)
 		}
-	}()
 
-	// leaving dir empty will create a temp dir
-	tempFile, err := os.CreateTemp("", "index.yaml")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file for response: %w", err)
-	}
+		defer func() {
+			if cerr := tempFile.Close(); cerr != nil {
+				err = errors.Join(err, cerr)
+			}
+		}()
 
-	defer func() {
-		if cerr := tempFile.Close(); cerr != nil {
-			err = errors.Join(err, cerr)
+		if _, err := io.Copy(tempFile, resp.Body); err != nil {
+			return nil, fmt.Errorf("failed to copy content to file: %w", err)
 		}
-	}()
 
-	if _, err := io.Copy(tempFile, resp.Body); err != nil {
-		return nil, fmt.Errorf("failed to copy content to file: %w", err)
-	}
-
-	// NOTE: This can be improved with a streaming reader if the need really arises.
-	content, err := os.ReadFile(tempFile.Name())
-	if err != nil {
-		return nil, fmt.Errorf("failed to read downloaded file: %w", err)
-	}
+		// NOTE: This can be improved with a streaming reader if the need really arises.
+		content, err := os.ReadFile(tempFile.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read downloaded file: %w", err)
+		}
 
-	res := &results{}
-	if err := yaml.Unmarshal(content, &res); err != nil {
-		return nil, err
-	}
+		res := &results{}
+		if err := yaml.Unmarshal(content, &res); err != nil {
+			return nil, err
+		}
 
-	v, ok := res.Entries[chartRef.ChartName]
-	if !ok {
-		return nil, fmt.Errorf("no charts found in registry with name %s", chartRef.ChartName)
-	}
+		v, ok := res.Entries[chartRef.ChartName]
+		if !ok {
+			return nil, fmt.Errorf("no charts found in registry with name %s", chartRef.ChartName)
+		}
 
-	versions := make([]string, 0, len(v))
-	for _, e := range v {
-		versions = append(versions, e.Version)
-	}
+		versions := make([]string, 0, len(v))
+		for _, e := range v {
+			versions = append(versions, e.Version)
+		}
 
-	return versions, nil
+		return versions, nil
+	})
 }
 
-func (s *Source) configureTransportForOCIRepo(ctx context.Context, src *remote.Repository, ref *v1.LocalObjectReference, namespace string) (err error) {
+func (s *Source) configureTransportForOCIRepo(ctx context.Context, httpClient *http.Client, src *remote.Repository, ref *v1.LocalObjectReference, namespace string) (err error) {
 	secret := &v1.Secret{}
 	if err := s.client.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace}, secret); err != nil {
 		return fmt.Errorf("failed to find attached secret: %w", err)
@@ -415,6 +487,8 @@ func (s *Source) configureTransportForOCIRepo(ctx context.Context, src *remote.R
 	}
 
 	c := &auth.Client{
+		Client: httpClient,
+		Cache:  auth.NewCache(),
 		Credential: func(_ context.Context, _ string) (auth.Credential, error) {
 			return auth.Credential{
 				Username: authForHost.Username,
@@ -428,7 +502,7 @@ func (s *Source) configureTransportForOCIRepo(ctx context.Context, src *remote.R
 	return nil
 }
 
-func (s *Source) configureOCICredentials(secret *v1.Secret, ref string, download *downloader.ChartDownloader) error {
+func (s *Source) configureOCICredentials(secret *v1.Secret, ref string, download *downloader.ChartDownloader, httpClient *http.Client) error {
 	config, ok := secret.Data[v1alpha1.DockerJSONConfigKey]
 	if !ok {
 		return errors.New("dockerjsonconfig is needed in secret to access OCI repository")
@@ -478,6 +552,10 @@ func (s *Source) configureOCICredentials(secret *v1.Secret, ref string, download
 		registry.ClientOptCredentialsFile(tmpConfig.Name()),
 	}
 
+	if httpClient != nil {
+		opts = append(opts, registry.ClientOptHTTPClient(httpClient))
+	}
+
 	registryClient, err := registry.NewClient(opts...)
 	if err != nil {
 		return fmt.Errorf("failed to create registry: %w", err)
@@ -492,7 +570,7 @@ func (s *Source) configureOCICredentials(secret *v1.Secret, ref string, download
 	return nil
 }
 
-func (s *Source) configureHTTPCredentials(ctx context.Context, secret *v1.Secret) (*http.Client, error) {
+func (s *Source) configureHTTPCredentials(ctx context.Context, base *http.Client, secret *v1.Secret) (*http.Client, error) {
 	token, ok := secret.Data[v1alpha1.PasswordKey]
 	if !ok {
 		return nil, errors.New("missing password key")
@@ -502,7 +580,14 @@ func (s *Source) configureHTTPCredentials(ctx context.Context, secret *v1.Secret
 		&oauth2.Token{AccessToken: string(token)},
 	)
 
-	return oauth2.NewClient(ctx, ts), nil
+	if base == nil {
+		return oauth2.NewClient(ctx, ts), nil
+	}
+
+	c := oauth2.NewClient(context.WithValue(ctx, oauth2.HTTPClient, base), ts)
+	c.Timeout = base.Timeout
+
+	return c, nil
 }
 
 func (s *Source) appendFilesToCrds(root string, crds *os.File) error {
@@ -1,6 +1,8 @@
 package helm
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -8,6 +10,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+	"github.com/Skarlso/crd-bootstrap/pkg/source"
 )
 
 func TestAppendFilesToCrds(t *testing.T) {
@@ -103,3 +108,31 @@ func TestAppendFilesToCrdsErrorOnInvalidRoot(t *testing.T) {
 	err = s.appendFilesToCrds("/nonexistent/path", crds)
 	assert.Error(t, err)
 }
+
+func TestFindVersionsForHTTPRepositoryRevalidatesIndex(t *testing.T) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"index-1"` {
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		downloads++
+
+		w.Header().Set("ETag", `"index-1"`)
+		_, _ = w.Write([]byte("entries:\n  chart:\n  - version: 1.0.0\n  - version: 1.1.0\n"))
+	}))
+	defer server.Close()
+
+	s := NewSource(server.Client(), nil, nil, source.NewConditionalCache(source.DefaultConditionalCacheTTL))
+	chart := &v1alpha1.Helm{ChartReference: server.URL, ChartName: "chart"}
+
+	for range 2 {
+		versions, err := s.findVersionsForHTTPRepository(t.Context(), server.Client(), chart, "default", "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1.0.0", "1.1.0"}, versions)
+	}
+
+	assert.Equal(t, 1, downloads)
+}
@@ -0,0 +1,207 @@
+package source
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+// DefaultTimeout is the timeout of requests to sources that don't define one.
+const DefaultTimeout = 60 * time.Second
+
+// NewTransport returns the round tripper requests to sources are sent with. Requests are sent through base, traced,
+// and retried if they failed with a retryable error. Every attempt of a retried request is traced.
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	return NewRetryTransport(otelhttp.NewTransport(base))
+}
+
+// NewHTTPClient creates a client dedicated to the source of the Bootstrap. Requests are sent through the transport
+// of base, unless the source defines a CA bundle, client certificate or proxy, in which case a transport with these
+// settings is built. base is never modified.
+func NewHTTPClient(ctx context.Context, c client.Client, base *http.Client, obj *v1alpha1.Bootstrap) (*http.Client, error) {
+	var settings v1alpha1.Transport
+	if t := obj.Spec.Source.GetTransport(); t != nil {
+		settings = *t
+	}
+
+	transport, err := NewHTTPTransport(ctx, c, obj.Namespace, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	dedicated := &http.Client{Timeout: Timeout(settings)}
+
+	switch {
+	case transport != nil:
+		dedicated.Transport = NewTransport(transport)
+	case base != nil:
+		dedicated.Transport = base.Transport
+		dedicated.CheckRedirect = base.CheckRedirect
+		dedicated.Jar = base.Jar
+	}
+
+	return dedicated, nil
+}
+
+// Timeout returns the timeout of requests to a source with the given transport settings.
+func Timeout(settings v1alpha1.Transport) time.Duration {
+	if settings.Timeout == nil || settings.Timeout.Duration <= 0 {
+		return DefaultTimeout
+	}
+
+	return settings.Timeout.Duration
+}
+
+// TransportKey identifies the transport settings of the source of the Bootstrap, so lookups made with a different CA
+// bundle, client certificate, proxy or timeout aren't shared. It's empty if the source doesn't define any.
+func TransportKey(obj *v1alpha1.Bootstrap) string {
+	settings := obj.Spec.Source.GetTransport()
+	if settings == nil {
+		return ""
+	}
+
+	var parts []string
+
+	if settings.CertSecretRef != nil {
+		parts = append(parts, "cert="+obj.Namespace+"/"+settings.CertSecretRef.Name)
+	}
+
+	if settings.ProxySecretRef != nil {
+		parts = append(parts, "proxy="+obj.Namespace+"/"+settings.ProxySecretRef.Name)
+	}
+
+	if settings.Timeout != nil {
+		parts = append(parts, "timeout="+Timeout(*settings).String())
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// NewHTTPTransport builds a transport that verifies servers with the CA bundle, authenticates with the client
+// certificate and sends requests through the proxy defined by the settings. It returns nil if none of them are
+// defined, so the shared transport can be used instead. Connections aren't kept alive, because the transport is
+// only used for a single reconciliation.
+func NewHTTPTransport(ctx context.Context, c client.Client, namespace string, settings v1alpha1.Transport) (*http.Transport, error) {
+	if settings.CertSecretRef == nil && settings.ProxySecretRef == nil {
+		return nil, nil
+	}
+
+	defaultTransport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("default transport isn't an http transport")
+	}
+
+	transport := defaultTransport.Clone()
+	transport.DisableKeepAlives = true
+
+	if settings.CertSecretRef != nil {
+		secret, err := getSecret(ctx, c, namespace, settings.CertSecretRef.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find cert secret: %w", err)
+		}
+
+		config, err := tlsConfig(secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure TLS with secret %s: %w", secret.Name, err)
+		}
+
+		transport.TLSClientConfig = config
+	}
+
+	if settings.ProxySecretRef != nil {
+		secret, err := getSecret(ctx, c, namespace, settings.ProxySecretRef.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find proxy secret: %w", err)
+		}
+
+		proxy, err := proxyURL(secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure proxy with secret %s: %w", secret.Name, err)
+		}
+
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	return transport, nil
+}
+
+func getSecret(ctx context.Context, c client.Client, namespace, name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// tlsConfig returns a TLS configuration that trusts the system roots and the CA bundle of the secret, and presents
+// its client certificate.
+func tlsConfig(secret *corev1.Secret) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if ca, ok := secret.Data[v1alpha1.CACertKey]; ok {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found under %s", v1alpha1.CACertKey)
+		}
+
+		config.RootCAs = pool
+	}
+
+	cert, hasCert := secret.Data[v1alpha1.TLSCertKey]
+	key, hasKey := secret.Data[v1alpha1.TLSKeyKey]
+
+	switch {
+	case hasCert && hasKey:
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client certificate: %w", err)
+		}
+
+		config.Certificates = []tls.Certificate{pair}
+	case hasCert || hasKey:
+		return nil, fmt.Errorf("both %s and %s are needed for a client certificate", v1alpha1.TLSCertKey, v1alpha1.TLSKeyKey)
+	}
+
+	return config, nil
+}
+
+// proxyURL returns the address of the proxy defined by the secret, including its credentials.
+func proxyURL(secret *corev1.Secret) (*url.URL, error) {
+	address, ok := secret.Data[v1alpha1.ProxyAddressKey]
+	if !ok {
+		return nil, fmt.Errorf("%s key not found", v1alpha1.ProxyAddressKey)
+	}
+
+	proxy, err := url.Parse(string(address))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proxy address: %w", err)
+	}
+
+	if proxy.Scheme == "" || proxy.Host == "" {
+		return nil, fmt.Errorf("proxy address %q must contain a scheme and host", address)
+	}
+
+	if username, ok := secret.Data[v1alpha1.UsernameKey]; ok {
+		proxy.User = url.UserPassword(string(username), string(secret.Data[v1alpha1.PasswordKey]))
+	}
+
+	return proxy, nil
+}
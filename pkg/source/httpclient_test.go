@@ -0,0 +1,305 @@
+package source
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+func urlBootstrap(address string, transport v1alpha1.Transport) *v1alpha1.Bootstrap {
+	return &v1alpha1.Bootstrap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: v1alpha1.BootstrapSpec{
+			Source: &v1alpha1.Source{URL: &v1alpha1.URL{URL: address, Transport: transport}},
+		},
+	}
+}
+
+func secret(name string, data map[string]string) *corev1.Secret {
+	s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}, Data: map[string][]byte{}}
+	for k, v := range data {
+		s.Data[k] = []byte(v)
+	}
+
+	return s
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	base := &http.Client{Transport: http.DefaultTransport}
+
+	tests := []struct {
+		name            string
+		transport       v1alpha1.Transport
+		expectedTimeout time.Duration
+	}{
+		{
+			name:            "shared transport with default timeout",
+			expectedTimeout: DefaultTimeout,
+		},
+		{
+			name:            "timeout of the source",
+			transport:       v1alpha1.Transport{Timeout: &metav1.Duration{Duration: 5 * time.Second}},
+			expectedTimeout: 5 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewHTTPClient(t.Context(), nil, base, urlBootstrap("https://example.com", tt.transport))
+			require.NoError(t, err)
+
+			assert.NotSame(t, base, c)
+			assert.Equal(t, tt.expectedTimeout, c.Timeout)
+			assert.Equal(t, http.DefaultTransport, c.Transport)
+			assert.Zero(t, base.Timeout)
+		})
+	}
+}
+
+func TestTransportKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport v1alpha1.Transport
+		expected  string
+	}{
+		{
+			name: "no settings",
+		},
+		{
+			name: "every setting",
+			transport: v1alpha1.Transport{
+				CertSecretRef:  &corev1.LocalObjectReference{Name: "ca"},
+				ProxySecretRef: &corev1.LocalObjectReference{Name: "proxy"},
+				Timeout:        &metav1.Duration{Duration: 5 * time.Second},
+			},
+			expected: "cert=default/ca,proxy=default/proxy,timeout=5s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, TransportKey(urlBootstrap("https://example.com", tt.transport)))
+		})
+	}
+}
+
+func TestNewHTTPClientVerifiesServerWithCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	kube := fake.NewClientBuilder().WithObjects(secret("ca", map[string]string{v1alpha1.CACertKey: string(ca)})).Build()
+
+	c, err := NewHTTPClient(t.Context(), kube, &http.Client{}, urlBootstrap(server.URL, v1alpha1.Transport{}))
+	require.NoError(t, err)
+
+	_, err = get(t, c, server.URL)
+	require.Error(t, err)
+
+	c, err = NewHTTPClient(t.Context(), kube, &http.Client{}, urlBootstrap(server.URL, v1alpha1.Transport{
+		CertSecretRef: &corev1.LocalObjectReference{Name: "ca"},
+	}))
+	require.NoError(t, err)
+
+	res, err := get(t, c, server.URL)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestNewHTTPClientPresentsClientCertificate(t *testing.T) {
+	cert, key := clientCertificate(t)
+
+	block, _ := pem.Decode(cert)
+	parsed, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(parsed)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	kube := fake.NewClientBuilder().WithObjects(
+		secret("ca", map[string]string{v1alpha1.CACertKey: string(ca)}),
+		secret("mtls", map[string]string{v1alpha1.CACertKey: string(ca), v1alpha1.TLSCertKey: string(cert), v1alpha1.TLSKeyKey: string(key)}),
+	).Build()
+
+	tests := []struct {
+		name        string
+		secret      string
+		expectedErr bool
+	}{
+		{
+			name:        "without client certificate",
+			secret:      "ca",
+			expectedErr: true,
+		},
+		{
+			name:   "with client certificate",
+			secret: "mtls",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Without retries, because the rejected client certificate can't be told apart from other errors.
+			transport, err := NewHTTPTransport(t.Context(), kube, "default", v1alpha1.Transport{
+				CertSecretRef: &corev1.LocalObjectReference{Name: tt.secret},
+			})
+			require.NoError(t, err)
+
+			res, err := get(t, &http.Client{Transport: transport}, server.URL)
+			if tt.expectedErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+		})
+	}
+}
+
+func TestNewHTTPClientSendsRequestsThroughProxy(t *testing.T) {
+	var (
+		requested     string
+		authorization string
+	)
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+		authorization = r.Header.Get("Proxy-Authorization")
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	kube := fake.NewClientBuilder().WithObjects(secret("proxy", map[string]string{
+		v1alpha1.ProxyAddressKey: proxy.URL,
+		v1alpha1.UsernameKey:     "user",
+		v1alpha1.PasswordKey:     "pass",
+	})).Build()
+
+	c, err := NewHTTPClient(t.Context(), kube, nil, urlBootstrap("http://crds.example.com/crds.yaml", v1alpha1.Transport{
+		ProxySecretRef: &corev1.LocalObjectReference{Name: "proxy"},
+	}))
+	require.NoError(t, err)
+
+	res, err := get(t, c, "http://crds.example.com/crds.yaml")
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	assert.Equal(t, "http://crds.example.com/crds.yaml", requested)
+	assert.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("user:pass")), authorization)
+}
+
+func TestNewHTTPTransportErrors(t *testing.T) {
+	cert, _ := clientCertificate(t)
+
+	tests := []struct {
+		name        string
+		transport   v1alpha1.Transport
+		expectedErr string
+	}{
+		{
+			name:        "missing secret",
+			transport:   v1alpha1.Transport{CertSecretRef: &corev1.LocalObjectReference{Name: "missing"}},
+			expectedErr: "failed to find cert secret",
+		},
+		{
+			name:        "invalid CA bundle",
+			transport:   v1alpha1.Transport{CertSecretRef: &corev1.LocalObjectReference{Name: "invalid-ca"}},
+			expectedErr: "no certificates found under ca.crt",
+		},
+		{
+			name:        "client certificate without key",
+			transport:   v1alpha1.Transport{CertSecretRef: &corev1.LocalObjectReference{Name: "cert-only"}},
+			expectedErr: "both tls.crt and tls.key are needed",
+		},
+		{
+			name:        "proxy without address",
+			transport:   v1alpha1.Transport{ProxySecretRef: &corev1.LocalObjectReference{Name: "invalid-ca"}},
+			expectedErr: "address key not found",
+		},
+		{
+			name:        "proxy address without scheme",
+			transport:   v1alpha1.Transport{ProxySecretRef: &corev1.LocalObjectReference{Name: "proxy"}},
+			expectedErr: "must contain a scheme and host",
+		},
+	}
+
+	kube := fake.NewClientBuilder().WithObjects(
+		secret("invalid-ca", map[string]string{v1alpha1.CACertKey: "not a certificate"}),
+		secret("cert-only", map[string]string{v1alpha1.TLSCertKey: string(cert)}),
+		secret("proxy", map[string]string{v1alpha1.ProxyAddressKey: "proxy.example.com:3128"}),
+	).Build()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHTTPTransport(t.Context(), kube, "default", tt.transport)
+			require.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func get(t *testing.T, c *http.Client, address string) (*http.Response, error) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, address, nil)
+	require.NoError(t, err)
+
+	return c.Do(req)
+}
+
+// clientCertificate returns a self-signed client certificate and its key.
+func clientCertificate(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "crd-bootstrap"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
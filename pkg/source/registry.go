@@ -0,0 +1,216 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+// Type defines the type of source a Bootstrap can define.
+type Type string
+
+const (
+	GitHub    Type = "github"
+	GitLab    Type = "gitlab"
+	Helm      Type = "helm"
+	ConfigMap Type = "configMap"
+	URL       Type = "url"
+)
+
+var (
+	// ErrNoSource is returned when a Bootstrap doesn't define any source.
+	ErrNoSource = errors.New("no source defined")
+	// ErrMultipleSources is returned when a Bootstrap defines more than one source.
+	ErrMultipleSources = errors.New("exactly one source must be defined")
+)
+
+// DefinedTypes returns the types of all sources that are set.
+func DefinedTypes(src *v1alpha1.Source) []Type {
+	if src == nil {
+		return nil
+	}
+
+	var types []Type
+
+	if src.GitHub != nil {
+		types = append(types, GitHub)
+	}
+
+	if src.GitLab != nil {
+		types = append(types, GitLab)
+	}
+
+	if src.Helm != nil {
+		types = append(types, Helm)
+	}
+
+	if src.ConfigMap != nil {
+		types = append(types, ConfigMap)
+	}
+
+	if src.URL != nil {
+		types = append(types, URL)
+	}
+
+	return types
+}
+
+// TypeOf returns the type of the single source that is set. It returns an error if none or multiple are set.
+func TypeOf(src *v1alpha1.Source) (Type, error) {
+	types := DefinedTypes(src)
+
+	switch len(types) {
+	case 0:
+		return "", ErrNoSource
+	case 1:
+		return types[0], nil
+	default:
+		names := make([]string, 0, len(types))
+		for _, t := range types {
+			names = append(names, string(t))
+		}
+
+		return "", fmt.Errorf("%w, found: %s", ErrMultipleSources, strings.Join(names, ", "))
+	}
+}
+
+// Registry holds a provider for each source type and resolves the one to use for a Bootstrap. Fetched CRDs and
+// samples are cached in the artifact store, if it's set.
+type Registry struct {
+	providers map[Type]Contract
+	artifacts *ArtifactStore
+}
+
+var (
+	_ Contract       = &Registry{}
+	_ SampleProvider = &Registry{}
+)
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[Type]Contract)}
+}
+
+// Register adds a provider for the given source type. Registering a type twice replaces the previous provider.
+func (r *Registry) Register(t Type, provider Contract) *Registry {
+	r.providers[t] = provider
+
+	return r
+}
+
+// WithArtifactStore caches the CRDs and samples fetched by every provider in the store.
+func (r *Registry) WithArtifactStore(artifacts *ArtifactStore) *Registry {
+	r.artifacts = artifacts
+
+	return r
+}
+
+// Resolve returns the provider for the single source defined by the Bootstrap.
+func (r *Registry) Resolve(obj *v1alpha1.Bootstrap) (Contract, error) {
+	t, err := TypeOf(obj.Spec.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, ok := r.providers[t]
+	if !ok {
+		return nil, fmt.Errorf("no provider registered for source type %s", t)
+	}
+
+	return provider, nil
+}
+
+// FetchCRD resolves the provider of the Bootstrap and fetches the CRD through it.
+func (r *Registry) FetchCRD(ctx context.Context, dir string, obj *v1alpha1.Bootstrap, revision string) (string, error) {
+	provider, err := r.Resolve(obj)
+	if err != nil {
+		return "", err
+	}
+
+	return r.cached(obj, revision, "", filepath.Join(dir, "crds.yaml"), func() (string, error) {
+		return provider.FetchCRD(ctx, dir, obj, revision)
+	})
+}
+
+// HasUpdate resolves the provider of the Bootstrap and checks for an update through it.
+func (r *Registry) HasUpdate(ctx context.Context, obj *v1alpha1.Bootstrap) (bool, string, error) {
+	provider, err := r.Resolve(obj)
+	if err != nil {
+		return false, "", err
+	}
+
+	return provider.HasUpdate(ctx, obj)
+}
+
+// FetchSamples resolves the provider of the Bootstrap and fetches samples through it if it supports samples.
+func (r *Registry) FetchSamples(ctx context.Context, dir string, obj *v1alpha1.Bootstrap, revision, path string) (string, error) {
+	provider, err := r.Resolve(obj)
+	if err != nil {
+		return "", err
+	}
+
+	samples, ok := provider.(SampleProvider)
+	if !ok {
+		t, _ := TypeOf(obj.Spec.Source)
+
+		return "", fmt.Errorf("source type %s doesn't provide samples", t)
+	}
+
+	return r.cached(obj, revision, path, filepath.Join(dir, "samples.yaml"), func() (string, error) {
+		return samples.FetchSamples(ctx, dir, obj, revision, path)
+	})
+}
+
+// cached copies the content stored for the revision and samples path of the Bootstrap to location. If nothing is
+// stored, the content is fetched and stored. ConfigMaps aren't cached, because they are read from the cluster and
+// their content can change without a new version.
+func (r *Registry) cached(obj *v1alpha1.Bootstrap, revision, path, location string, fetch func() (string, error)) (_ string, err error) {
+	if r.artifacts == nil || revision == "" || obj.Spec.Source.ConfigMap != nil {
+		return fetch()
+	}
+
+	key, err := ArtifactKey(obj, revision)
+	if err != nil {
+		return "", err
+	}
+
+	if path != "" {
+		key += "|samples|" + path
+	}
+
+	stored, err := r.artifacts.Copy(key, location)
+	if err != nil {
+		return "", fmt.Errorf("failed to copy stored artifact: %w", err)
+	}
+
+	if stored {
+		return location, nil
+	}
+
+	fetched, err := fetch()
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(filepath.Clean(fetched))
+	if err != nil {
+		return "", fmt.Errorf("failed to open fetched artifact: %w", err)
+	}
+
+	defer func() {
+		if cerr := file.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+	}()
+
+	if err := r.artifacts.Put(key, file); err != nil {
+		return "", fmt.Errorf("failed to store fetched artifact: %w", err)
+	}
+
+	return fetched, nil
+}
@@ -0,0 +1,179 @@
+package source
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+)
+
+type fakeProvider struct {
+	name string
+}
+
+func (f *fakeProvider) FetchCRD(_ context.Context, _ string, _ *v1alpha1.Bootstrap, _ string) (string, error) {
+	return f.name, nil
+}
+
+func (f *fakeProvider) HasUpdate(_ context.Context, _ *v1alpha1.Bootstrap) (bool, string, error) {
+	return true, f.name, nil
+}
+
+func TestRegistryResolve(t *testing.T) {
+	registry := NewRegistry().
+		Register(GitHub, &fakeProvider{name: "github"}).
+		Register(Helm, &fakeProvider{name: "helm"})
+
+	tests := []struct {
+		name        string
+		src         *v1alpha1.Source
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:     "resolves the defined source",
+			src:      &v1alpha1.Source{Helm: &v1alpha1.Helm{}},
+			expected: "helm",
+		},
+		{
+			name:        "no source",
+			expectedErr: "no source defined",
+		},
+		{
+			name:        "empty source",
+			src:         &v1alpha1.Source{},
+			expectedErr: "no source defined",
+		},
+		{
+			name:        "multiple sources",
+			src:         &v1alpha1.Source{GitHub: &v1alpha1.GitHub{}, Helm: &v1alpha1.Helm{}},
+			expectedErr: "exactly one source must be defined, found: github, helm",
+		},
+		{
+			name:        "unregistered source",
+			src:         &v1alpha1.Source{URL: &v1alpha1.URL{}},
+			expectedErr: "no provider registered for source type url",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &v1alpha1.Bootstrap{Spec: v1alpha1.BootstrapSpec{Source: tt.src}}
+
+			_, revision, err := registry.HasUpdate(t.Context(), obj)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, revision)
+		})
+	}
+}
+
+func TestRegistryFetchSamplesUnsupported(t *testing.T) {
+	registry := NewRegistry().Register(URL, &fakeProvider{name: "url"})
+	obj := &v1alpha1.Bootstrap{Spec: v1alpha1.BootstrapSpec{Source: &v1alpha1.Source{URL: &v1alpha1.URL{}}}}
+
+	_, err := registry.FetchSamples(t.Context(), t.TempDir(), obj, "v1", "samples.yaml")
+	require.EqualError(t, err, "source type url doesn't provide samples")
+}
+
+type countingProvider struct {
+	fakeProvider
+	fetches int
+}
+
+func (c *countingProvider) FetchCRD(_ context.Context, dir string, _ *v1alpha1.Bootstrap, revision string) (string, error) {
+	c.fetches++
+
+	location := filepath.Join(dir, "provider.yaml")
+
+	return location, os.WriteFile(location, []byte("revision: "+revision), 0o600)
+}
+
+func TestRegistryCachesFetchedCRDs(t *testing.T) {
+	github := &v1alpha1.Source{GitHub: &v1alpha1.GitHub{Owner: "owner", Repo: "repo"}}
+
+	tests := []struct {
+		name            string
+		src             *v1alpha1.Source
+		second          func(obj *v1alpha1.Bootstrap) (*v1alpha1.Bootstrap, string)
+		expectedFetches int
+	}{
+		{
+			name: "same source and revision",
+			src:  github,
+			second: func(obj *v1alpha1.Bootstrap) (*v1alpha1.Bootstrap, string) {
+				return obj, "v1"
+			},
+			expectedFetches: 1,
+		},
+		{
+			name: "different revision",
+			src:  github,
+			second: func(obj *v1alpha1.Bootstrap) (*v1alpha1.Bootstrap, string) {
+				return obj, "v2"
+			},
+			expectedFetches: 2,
+		},
+		{
+			name: "different namespace",
+			src:  github,
+			second: func(obj *v1alpha1.Bootstrap) (*v1alpha1.Bootstrap, string) {
+				other := obj.DeepCopy()
+				other.Namespace = "other"
+
+				return other, "v1"
+			},
+			expectedFetches: 2,
+		},
+		{
+			name: "config maps aren't cached",
+			src:  &v1alpha1.Source{ConfigMap: &v1alpha1.ConfigMap{Name: "crds"}},
+			second: func(obj *v1alpha1.Bootstrap) (*v1alpha1.Bootstrap, string) {
+				return obj, "v1"
+			},
+			expectedFetches: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewArtifactStore(t.TempDir(), 0)
+			require.NoError(t, err)
+
+			provider := &countingProvider{}
+			registry := NewRegistry().
+				Register(GitHub, provider).
+				Register(ConfigMap, provider).
+				WithArtifactStore(store)
+
+			obj := &v1alpha1.Bootstrap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec:       v1alpha1.BootstrapSpec{Source: tt.src},
+			}
+
+			_, err = registry.FetchCRD(t.Context(), t.TempDir(), obj, "v1")
+			require.NoError(t, err)
+
+			second, revision := tt.second(obj)
+
+			location, err := registry.FetchCRD(t.Context(), t.TempDir(), second, revision)
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(location)
+			require.NoError(t, err)
+			assert.Equal(t, "revision: "+revision, string(content))
+			assert.Equal(t, tt.expectedFetches, provider.fetches)
+		})
+	}
+}
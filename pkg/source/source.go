@@ -10,7 +10,7 @@ import (
 type Contract interface {
 	// FetchCRD fetches the latest CRD if there is an update available.
 	// The returned thing is the location to the CRD. This function should not return the CRD content
-	// as it could be several megabytes large.
+	// as it could be several megabytes large. If the revision is a digest, the fetched content has to match it.
 	FetchCRD(ctx context.Context, dir string, obj *v1alpha1.Bootstrap, revision string) (string, error)
 	// HasUpdate returns true and the version if there is an update available.
 	// In case of a URL this would be the digest. This logic follows this general guide:
@@ -20,3 +20,11 @@ type Contract interface {
 	// - Return false and empty string if there is nothing to apply.
 	HasUpdate(ctx context.Context, obj *v1alpha1.Bootstrap) (bool, string, error)
 }
+
+// SampleProvider is implemented by sources which can provide sample objects for a given revision.
+// These samples are used to validate the fetched CRDs before they are applied.
+type SampleProvider interface {
+	// FetchSamples fetches the samples found at path in the source at the given revision.
+	// The returned thing is the location of a YAML file containing all the samples.
+	FetchSamples(ctx context.Context, dir string, obj *v1alpha1.Bootstrap, revision, path string) (string, error)
+}
@@ -0,0 +1,289 @@
+package source
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when the source doesn't have the requested repository, release, chart or file.
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited is returned when the source rejected a request because of a rate limit.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnreachable is returned when the source couldn't be reached or failed to handle a request.
+	ErrUnreachable = errors.New("unreachable")
+)
+
+// StatusError is returned for responses with an unsuccessful status code. It matches ErrNotFound, ErrRateLimited or
+// ErrUnreachable with errors.Is, depending on the status code.
+type StatusError struct {
+	// StatusCode of the response.
+	StatusCode int
+	// Status of the response, such as "404 Not Found".
+	Status string
+	// RetryAfter is how long to wait until the rate limit is lifted, if the response announced it.
+	RetryAfter time.Duration
+
+	rateLimited bool
+}
+
+// NewStatusError creates a StatusError for the response.
+func NewStatusError(res *http.Response) *StatusError {
+	status := res.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", res.StatusCode, http.StatusText(res.StatusCode))
+	}
+
+	wait, limited := rateLimitWait(res, time.Now())
+
+	return &StatusError{StatusCode: res.StatusCode, Status: status, RetryAfter: wait, rateLimited: limited}
+}
+
+func (e *StatusError) Error() string {
+	return "unexpected status " + e.Status
+}
+
+// Is reports whether the status code of the error falls into the class of target.
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.rateLimited
+	case ErrUnreachable:
+		return e.StatusCode >= http.StatusInternalServerError
+	default:
+		return false
+	}
+}
+
+// RetryAfter returns how long to wait before retrying a request that failed with err because of a rate limit. It
+// returns false if err isn't caused by a rate limit or the source didn't say how long to wait.
+func RetryAfter(err error) (time.Duration, bool) {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || !statusErr.rateLimited || statusErr.RetryAfter <= 0 {
+		return 0, false
+	}
+
+	return statusErr.RetryAfter, true
+}
+
+// RetryTransport retries idempotent requests that failed because the server couldn't be reached, answered with a
+// server error or was rate limited. Retries are delayed by an exponential backoff with jitter, or by as long as the
+// server asks for through the Retry-After or rate limit reset headers.
+type RetryTransport struct {
+	// Base sends the requests.
+	Base http.RoundTripper
+	// MaxRetries is the number of times a request is retried.
+	MaxRetries int
+	// MinBackoff is the delay before the first retry. It doubles with every retry.
+	MinBackoff time.Duration
+	// MaxBackoff is the longest delay between two retries.
+	MaxBackoff time.Duration
+	// MaxWait is the longest a rate limited request waits to be retried. If the rate limit is lifted later, the
+	// response is returned, so the caller can try again later instead of blocking.
+	MaxWait time.Duration
+
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+var _ http.RoundTripper = &RetryTransport{}
+
+// NewRetryTransport creates a RetryTransport with the default settings that sends requests through base.
+func NewRetryTransport(base http.RoundTripper) *RetryTransport {
+	return &RetryTransport{
+		Base:       base,
+		MaxRetries: 3,
+		MinBackoff: 500 * time.Millisecond,
+		MaxBackoff: 10 * time.Second,
+		MaxWait:    30 * time.Second,
+	}
+}
+
+// RoundTrip sends the request, retrying it if it's idempotent and failed with a retryable error. The request isn't
+// modified, every retry sends a clone of it with a fresh body.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !idempotent(req) {
+		res, err := t.Base.RoundTrip(req)
+
+		return res, unreachable(req, err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+
+		if attempt > 0 {
+			attemptReq = req.Clone(req.Context())
+
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, fmt.Errorf("failed to reset request body: %w", err)
+				}
+
+				attemptReq.Body = body
+			}
+		}
+
+		res, err := t.Base.RoundTrip(attemptReq)
+
+		wait, retry := t.retryDelay(res, err, attempt)
+		if !retry || attempt >= t.MaxRetries {
+			return res, unreachable(req, err)
+		}
+
+		if res != nil {
+			// Drain what's left of the body, so the connection can be reused.
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+
+		if err := t.wait(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryDelay returns how long to wait before retrying the request, or false if it shouldn't be retried.
+func (t *RetryTransport) retryDelay(res *http.Response, err error, attempt int) (time.Duration, bool) {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return 0, false
+		}
+
+		// Servers that can't be verified won't be trusted on the next attempt either.
+		var certErr *tls.CertificateVerificationError
+		if errors.As(err, &certErr) {
+			return 0, false
+		}
+
+		return t.backoff(attempt), true
+	}
+
+	if wait, limited := rateLimitWait(res, time.Now()); limited {
+		if wait > t.MaxWait {
+			return 0, false
+		}
+
+		if wait <= 0 {
+			wait = t.backoff(attempt)
+		}
+
+		return wait, true
+	}
+
+	switch res.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		// Servers that are down for maintenance might say when to come back.
+		if wait, ok := parseRetryAfter(res.Header.Get("Retry-After"), time.Now()); ok && wait <= t.MaxWait {
+			return wait, true
+		}
+
+		return t.backoff(attempt), true
+	default:
+		return 0, false
+	}
+}
+
+// backoff returns the delay before the retry after attempt, with jitter of up to half of the delay.
+func (t *RetryTransport) backoff(attempt int) time.Duration {
+	delay := t.MaxBackoff
+	if attempt < 32 {
+		delay = min(t.MinBackoff<<attempt, t.MaxBackoff)
+	}
+
+	if delay <= 1 {
+		return delay
+	}
+
+	half := delay / 2
+
+	return half + rand.N(delay-half) //nolint:gosec // jitter doesn't need a secure random source.
+}
+
+func (t *RetryTransport) wait(ctx context.Context, d time.Duration) error {
+	if t.sleep != nil {
+		return t.sleep(ctx, d)
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// unreachable marks errors of requests that weren't cancelled with ErrUnreachable.
+func unreachable(req *http.Request, err error) error {
+	if err == nil || req.Context().Err() != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", ErrUnreachable, err)
+}
+
+// idempotent reports whether the request can be sent again safely.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	default:
+		return false
+	}
+}
+
+// rateLimitWait reports whether the response rejected the request because of a rate limit, and how long to wait
+// until the limit is lifted if the response says so. GitHub rejects rate limited requests with 403, so a 403 is only
+// considered rate limited if it comes with rate limit headers.
+func rateLimitWait(res *http.Response, now time.Time) (time.Duration, bool) {
+	retryAfter, hasRetryAfter := parseRetryAfter(res.Header.Get("Retry-After"), now)
+
+	switch {
+	case res.StatusCode == http.StatusTooManyRequests:
+	case res.StatusCode == http.StatusForbidden && (hasRetryAfter || res.Header.Get("X-RateLimit-Remaining") == "0"):
+	default:
+		return 0, false
+	}
+
+	if hasRetryAfter {
+		return retryAfter, true
+	}
+
+	// GitHub sends X-RateLimit-Reset and GitLab RateLimit-Reset, both as seconds since the epoch.
+	for _, header := range []string{"X-RateLimit-Reset", "RateLimit-Reset"} {
+		reset, err := strconv.ParseInt(res.Header.Get(header), 10, 64)
+		if err == nil {
+			return max(time.Unix(reset, 0).Sub(now), 0), true
+		}
+	}
+
+	return 0, true
+}
+
+// parseRetryAfter parses a Retry-After header, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,286 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type response struct {
+	status int
+	header map[string]string
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name            string
+		method          string
+		responses       []response
+		expectedStatus  int
+		expectedWaits   []time.Duration
+		expectedRetries int
+	}{
+		{
+			name:            "server error is retried",
+			responses:       []response{{status: http.StatusBadGateway}, {status: http.StatusOK}},
+			expectedStatus:  http.StatusOK,
+			expectedRetries: 1,
+		},
+		{
+			name:            "gives up after max retries",
+			responses:       []response{{status: http.StatusServiceUnavailable}},
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedRetries: 2,
+		},
+		{
+			name:            "not found isn't retried",
+			responses:       []response{{status: http.StatusNotFound}},
+			expectedStatus:  http.StatusNotFound,
+			expectedRetries: 0,
+		},
+		{
+			name: "retry after is honoured",
+			responses: []response{
+				{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "3"}},
+				{status: http.StatusOK},
+			},
+			expectedStatus:  http.StatusOK,
+			expectedWaits:   []time.Duration{3 * time.Second},
+			expectedRetries: 1,
+		},
+		{
+			name: "rate limit that lasts too long isn't waited for",
+			responses: []response{
+				{status: http.StatusForbidden, header: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)}},
+			},
+			expectedStatus:  http.StatusForbidden,
+			expectedRetries: 0,
+		},
+		{
+			name:            "forbidden without rate limit headers isn't retried",
+			responses:       []response{{status: http.StatusForbidden}},
+			expectedStatus:  http.StatusForbidden,
+			expectedRetries: 0,
+		},
+		{
+			name:            "non idempotent requests aren't retried",
+			method:          http.MethodPost,
+			responses:       []response{{status: http.StatusBadGateway}},
+			expectedStatus:  http.StatusBadGateway,
+			expectedRetries: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				res := tt.responses[min(requests, len(tt.responses)-1)]
+				requests++
+
+				for k, v := range res.header {
+					w.Header().Set(k, v)
+				}
+
+				w.WriteHeader(res.status)
+			}))
+			defer server.Close()
+
+			var waits []time.Duration
+
+			transport := NewRetryTransport(http.DefaultTransport)
+			transport.MaxRetries = 2
+			transport.sleep = func(_ context.Context, d time.Duration) error {
+				waits = append(waits, d)
+
+				return nil
+			}
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			req, err := http.NewRequestWithContext(t.Context(), method, server.URL, nil)
+			require.NoError(t, err)
+
+			res, err := (&http.Client{Transport: transport}).Do(req)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.Equal(t, tt.expectedRetries+1, requests)
+
+			if tt.expectedWaits != nil {
+				assert.Equal(t, tt.expectedWaits, waits)
+			}
+		})
+	}
+}
+
+func TestRetryTransportDoesNotModifyRequest(t *testing.T) {
+	var bodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		bodies = append(bodies, string(body))
+
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	transport := NewRetryTransport(http.DefaultTransport)
+	transport.sleep = func(context.Context, time.Duration) error { return nil }
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL, strings.NewReader("query"))
+	require.NoError(t, err)
+
+	body := req.Body
+
+	res, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []string{"query", "query"}, bodies)
+	assert.Equal(t, body, req.Body, "the body of the request must not be replaced")
+}
+
+func TestRetryTransportUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	address := server.URL
+	server.Close()
+
+	transport := NewRetryTransport(http.DefaultTransport)
+	transport.sleep = func(context.Context, time.Duration) error { return nil }
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, address, nil)
+	require.NoError(t, err)
+
+	_, err = (&http.Client{Transport: transport}).Do(req)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrUnreachable)
+}
+
+func TestRetryTransportCertificateVerification(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			requests.Add(1)
+		}
+	}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	transport := NewRetryTransport(http.DefaultTransport)
+	transport.sleep = func(context.Context, time.Duration) error { return nil }
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	_, err = (&http.Client{Transport: transport}).Do(req)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrUnreachable)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestRetryTransportBackoff(t *testing.T) {
+	transport := NewRetryTransport(http.DefaultTransport)
+
+	for attempt, expected := range []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		delay := transport.backoff(attempt)
+
+		assert.GreaterOrEqual(t, delay, expected/2, attempt)
+		assert.LessOrEqual(t, delay, expected, attempt)
+	}
+
+	assert.LessOrEqual(t, transport.backoff(100), 10*time.Second)
+}
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		name               string
+		status             int
+		header             map[string]string
+		expected           error
+		expectedRetryAfter time.Duration
+	}{
+		{
+			name:     "not found",
+			status:   http.StatusNotFound,
+			expected: ErrNotFound,
+		},
+		{
+			name:               "too many requests",
+			status:             http.StatusTooManyRequests,
+			header:             map[string]string{"Retry-After": "120"},
+			expected:           ErrRateLimited,
+			expectedRetryAfter: 2 * time.Minute,
+		},
+		{
+			name:     "github rate limit",
+			status:   http.StatusForbidden,
+			header:   map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)},
+			expected: ErrRateLimited,
+		},
+		{
+			name:     "server error",
+			status:   http.StatusInternalServerError,
+			expected: ErrUnreachable,
+		},
+		{
+			name:   "forbidden",
+			status: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for k, v := range tt.header {
+				res.Header.Set(k, v)
+			}
+
+			err := NewStatusError(res)
+			assert.True(t, strings.HasPrefix(err.Error(), "unexpected status "+strconv.Itoa(tt.status)))
+
+			for _, class := range []error{ErrNotFound, ErrRateLimited, ErrUnreachable} {
+				assert.Equal(t, class == tt.expected, errors.Is(err, class), class)
+			}
+
+			retryAfter, ok := RetryAfter(err)
+			if tt.expected != ErrRateLimited {
+				assert.False(t, ok)
+
+				return
+			}
+
+			require.True(t, ok)
+
+			if tt.expectedRetryAfter > 0 {
+				assert.Equal(t, tt.expectedRetryAfter, retryAfter)
+			} else {
+				assert.InDelta(t, time.Hour, retryAfter, float64(time.Minute))
+			}
+		})
+	}
+}
@@ -21,87 +21,112 @@ import (
 type Source struct {
 	Client *http.Client
 
-	client client.Client
-	next   source.Contract
+	client      client.Client
+	conditional *source.ConditionalCache
+	artifacts   *source.ArtifactStore
 }
 
 var _ source.Contract = &Source{}
 
-// NewSource creates a new GitHub handling Source.
-func NewSource(c *http.Client, client client.Client, next source.Contract) *Source {
-	return &Source{Client: c, client: client, next: next}
+// NewSource creates a new URL handling Source. The digest of unchanged content is looked up through the
+// conditional cache instead of downloading it again, and content downloaded to check for updates is stored in the
+// artifact store, so the Registry hands it to FetchCRD. Both may be nil.
+func NewSource(c *http.Client, client client.Client, conditional *source.ConditionalCache, artifacts *source.ArtifactStore) *Source {
+	return &Source{Client: c, client: client, conditional: conditional, artifacts: artifacts}
 }
 
+// FetchCRD downloads the content with the digest revision. It's only called if the content downloaded by HasUpdate
+// is no longer stored, and fails if the content changed since.
 func (s *Source) FetchCRD(ctx context.Context, dir string, obj *v1alpha1.Bootstrap, revision string) (string, error) {
-	if obj.Spec.Source.URL == nil {
-		if s.next == nil {
-			return "", errors.New("url isn't defined and there are no other sources configured")
-		}
+	location := filepath.Join(dir, "crds.yaml")
 
-		return s.next.FetchCRD(ctx, dir, obj, revision)
+	if err := s.fetch(ctx, location, obj, revision); err != nil {
+		return "", fmt.Errorf("failed to fetch CRD: %w", err)
 	}
 
-	err := s.fetch(ctx, dir, obj)
+	return location, nil
+}
+
+func (s *Source) HasUpdate(ctx context.Context, obj *v1alpha1.Bootstrap) (bool, string, error) {
+	digest, err := s.digest(ctx, obj)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch CRD: %w", err)
+		return false, "", fmt.Errorf("failed to fetch CRD: %w", err)
 	}
 
-	return filepath.Join(dir, "crds.yaml"), nil
+	if obj.Spec.Version.Digest != "" {
+		// we will always apply it, it should be safe because there shouldn't be any changes.
+		if obj.Spec.Version.Digest == digest {
+			return true, obj.Spec.Version.Digest, nil
+		}
+
+		return false, "", nil
+	}
+
+	if obj.Status.LastAppliedRevision == digest {
+		return false, obj.Status.LastAppliedRevision, nil
+	}
+
+	return true, digest, nil
 }
 
-func (s *Source) HasUpdate(ctx context.Context, obj *v1alpha1.Bootstrap) (_ bool, _ string, err error) {
-	if obj.Spec.Source.URL == nil {
-		if s.next == nil {
-			return false, "", errors.New("github isn't defined and there are no other sources configured")
-		}
+// digest returns the sha256 digest of the content. The content isn't downloaded again if the server reports that it
+// didn't change since the last time.
+func (s *Source) digest(ctx context.Context, obj *v1alpha1.Bootstrap) (string, error) {
+	downloadURL := obj.Spec.Source.URL.URL
 
-		return s.next.HasUpdate(ctx, obj)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create HTTP request for %s, error: %w", downloadURL, err)
 	}
 
-	dir, err := os.MkdirTemp("", "crd-url")
+	c, credentials, err := s.httpClient(ctx, obj)
 	if err != nil {
-		return false, "", fmt.Errorf("failed to create temp folder: %w", err)
+		return "", err
 	}
 
-	defer func() {
-		if rerr := os.RemoveAll(dir); rerr != nil {
-			err = errors.Join(err, rerr)
+	digest, err := source.Conditional(s.conditional, c, req, credentials, source.TransportKey(obj), func(resp *http.Response) (string, error) {
+		if resp.StatusCode != http.StatusOK {
+			return "", source.NewStatusError(resp)
 		}
-	}()
 
-	if err := s.fetch(ctx, dir, obj); err != nil {
-		return false, "", fmt.Errorf("failed to fetch CRD: %w", err)
-	}
+		// Keep the content, so FetchCRD applies exactly what was hashed.
+		digest, err := s.artifacts.PutFunc(resp.Body, func(digest string) (string, error) {
+			return source.ArtifactKey(obj, digest)
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to store content of CRD: %w", err)
+		}
 
-	file, err := os.Open(filepath.Clean(filepath.Join(dir, "crds.yaml")))
+		return digest, nil
+	})
 	if err != nil {
-		return false, "", fmt.Errorf("failed to open file of downloaded CRD: %w", err)
+		return "", fmt.Errorf("failed to download content from %s, error: %w", downloadURL, err)
 	}
 
-	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return false, "", fmt.Errorf("failed to hash content of CRD: %w", err)
-	}
+	return digest, nil
+}
 
-	sum := hash.Sum(nil)
-	if obj.Spec.Version.Digest != "" {
-		// we will always apply it, it should be safe because there shouldn't be any changes.
-		if obj.Spec.Version.Digest == hex.EncodeToString(sum) {
-			return true, obj.Spec.Version.Digest, nil
-		}
+// httpClient returns the client to download the content with and the identity of its credentials.
+func (s *Source) httpClient(ctx context.Context, obj *v1alpha1.Bootstrap) (*http.Client, string, error) {
+	c, err := source.NewHTTPClient(ctx, s.client, s.Client, obj)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to construct HTTP client: %w", err)
+	}
 
-		return false, "", nil
+	if obj.Spec.Source.URL.SecretRef == nil {
+		return c, "", nil
 	}
 
-	if obj.Status.LastAppliedRevision == hex.EncodeToString(sum) {
-		return false, obj.Status.LastAppliedRevision, nil
+	c, err = auth.ConstructAuthenticatedClient(ctx, s.client, c, obj.Spec.Source.URL.SecretRef.Name, obj.Namespace)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to construct authenticated client: %w", err)
 	}
 
-	return true, hex.EncodeToString(sum), nil
+	return c, obj.Namespace + "/" + obj.Spec.Source.URL.SecretRef.Name, nil
 }
 
-// fetch fetches the content.
-func (s *Source) fetch(ctx context.Context, dir string, obj *v1alpha1.Bootstrap) (err error) {
+// fetch downloads the content to location and checks that its digest is revision.
+func (s *Source) fetch(ctx context.Context, location string, obj *v1alpha1.Bootstrap, revision string) (err error) {
 	downloadURL := obj.Spec.Source.URL.URL
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
@@ -110,12 +135,9 @@ func (s *Source) fetch(ctx context.Context, dir string, obj *v1alpha1.Bootstrap)
 	}
 
 	// download
-	c := s.Client
-	if obj.Spec.Source.URL.SecretRef != nil {
-		c, err = auth.ConstructAuthenticatedClient(ctx, s.client, obj.Spec.Source.URL.SecretRef.Name, obj.Namespace)
-		if err != nil {
-			return fmt.Errorf("failed to construct authenticated client: %w", err)
-		}
+	c, _, err := s.httpClient(ctx, obj)
+	if err != nil {
+		return err
 	}
 
 	resp, err := c.Do(req)
@@ -131,10 +153,10 @@ func (s *Source) fetch(ctx context.Context, dir string, obj *v1alpha1.Bootstrap)
 
 	// check response
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download content from %s, status: %s", downloadURL, resp.Status)
+		return fmt.Errorf("failed to download content from %s: %w", downloadURL, source.NewStatusError(resp))
 	}
 
-	wf, err := os.Create(filepath.Clean(filepath.Join(dir, "crds.yaml")))
+	wf, err := os.Create(filepath.Clean(location))
 	if err != nil {
 		return fmt.Errorf("failed to open temp file: %w", err)
 	}
@@ -145,9 +167,14 @@ func (s *Source) fetch(ctx context.Context, dir string, obj *v1alpha1.Bootstrap)
 		}
 	}()
 
-	if _, err := io.Copy(wf, resp.Body); err != nil {
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(wf, hash), resp.Body); err != nil {
 		return fmt.Errorf("failed to write to temp file: %w", err)
 	}
 
+	if digest := hex.EncodeToString(hash.Sum(nil)); digest != revision {
+		return fmt.Errorf("content of %s changed since it was checked for updates, expected digest %s but got %s", downloadURL, revision, digest)
+	}
+
 	return nil
 }
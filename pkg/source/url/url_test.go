@@ -0,0 +1,95 @@
+package url
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Skarlso/crd-bootstrap/api/v1alpha1"
+	"github.com/Skarlso/crd-bootstrap/pkg/source"
+)
+
+func TestFetchCRDAppliesCheckedContent(t *testing.T) {
+	tests := []struct {
+		name              string
+		artifacts         bool
+		expectedDownloads int
+		expectedErr       string
+	}{
+		{
+			name:              "content downloaded by HasUpdate is reused",
+			artifacts:         true,
+			expectedDownloads: 1,
+		},
+		{
+			name:              "content changed after HasUpdate",
+			expectedDownloads: 2,
+			expectedErr:       "changed since it was checked for updates",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			downloads := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				downloads++
+
+				if downloads == 1 {
+					_, _ = w.Write([]byte("kind: CustomResourceDefinition\n"))
+
+					return
+				}
+
+				_, _ = w.Write([]byte("kind: Changed\n"))
+			}))
+			defer server.Close()
+
+			var artifacts *source.ArtifactStore
+			if tt.artifacts {
+				var err error
+
+				artifacts, err = source.NewArtifactStore(t.TempDir(), 0)
+				require.NoError(t, err)
+			}
+
+			s := source.NewRegistry().
+				Register(source.URL, NewSource(server.Client(), nil, nil, artifacts)).
+				WithArtifactStore(artifacts)
+			obj := &v1alpha1.Bootstrap{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec: v1alpha1.BootstrapSpec{
+					Source: &v1alpha1.Source{URL: &v1alpha1.URL{URL: server.URL}},
+				},
+			}
+
+			update, revision, err := s.HasUpdate(t.Context(), obj)
+			require.NoError(t, err)
+			require.True(t, update)
+
+			sum := sha256.Sum256([]byte("kind: CustomResourceDefinition\n"))
+			assert.Equal(t, hex.EncodeToString(sum[:]), revision)
+
+			location, err := s.FetchCRD(t.Context(), t.TempDir(), obj, revision)
+			assert.Equal(t, tt.expectedDownloads, downloads)
+
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(location)
+			require.NoError(t, err)
+			assert.Equal(t, "kind: CustomResourceDefinition\n", string(content))
+		})
+	}
+}